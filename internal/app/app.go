@@ -3,6 +3,7 @@ package app
 import (
 	"os"
 	"time"
+	"vibe-check/internal/config"
 	"vibe-check/internal/git"
 	"vibe-check/internal/models"
 	"vibe-check/internal/ui"
@@ -13,9 +14,9 @@ import (
 // refreshMsg indicates it's time to refresh the disabled state
 type refreshMsg struct{}
 
-// doRefresh returns a command that sends refresh message after delay
+// doRefresh returns a command that sends refresh message after ui.refresh_interval
 func doRefresh() tea.Cmd {
-	return tea.Tick(time.Second*2, func(t time.Time) tea.Msg {
+	return tea.Tick(config.Duration("ui.refresh_interval"), func(t time.Time) tea.Msg {
 		return refreshMsg{}
 	})
 }
@@ -25,36 +26,60 @@ var MenuOptions = []string{
 	"Create Checkpoint",
 	"Change Checkpoint",
 	"Finalize and Push",
+	"Return to Branch",
+	"History (Undo/Redo)",
+	"Backups",
 	"Exit",
 }
 
 var CheckpointCreationOptions = []string{
 	"Create Checkpoint",
 	"Create Checkpoint with Custom Note",
+	"Create Checkpoint from Selected Files",
+	"Create Shadow Checkpoint (keep branch as is)",
 	"Back to Main Menu",
 }
 
 var FinalizeOptions = []string{
 	"Finalize and Push (Auto Message)",
 	"Finalize and Push with Custom Message",
+	"Finalize Locally (no push)",
 	"Back to Main Menu",
 }
 
+var FinalizeConfirmOptions = []string{
+	"Confirm and Finalize",
+	"Cancel",
+}
+
 // App wraps the models.AppModel and implements tea.Model
 type App struct {
 	models.AppModel
+
+	// Repository the TUI works on; replaced, never changed, when the runner changes
+	repo *git.Repo
+
+	// Auto-checkpoint watcher, see watch.go
+	watcher  *git.Watcher
+	watchGen int
+
+	// Incremented by every synchronous menu refresh
+	statusGen int
 }
 
-// InitialModel creates the initial application model
-func InitialModel() App {
+// InitialModel creates the initial application model for repo
+func InitialModel(repo *git.Repo) App {
 	app := App{
+		repo: repo,
 		AppModel: models.AppModel{
 			CurrentState:      models.StateMenu,
 			MenuChoices:       MenuOptions,
 			CheckpointOptions: CheckpointCreationOptions,
 			FinalizeOptions:   FinalizeOptions,
+			ConfirmOptions:    FinalizeConfirmOptions,
 			DisabledMenuItems: make(map[int]bool),
 			DisabledReasons:   make(map[int]string),
+			Scope:             repo.DescribeScope(),
 		},
 	}
 	// Update disabled items based on current state
@@ -64,10 +89,38 @@ func InitialModel() App {
 	return app
 }
 
+// statusLoadedMsg carries what the main menu shows about the repository
+type statusLoadedMsg struct {
+	Gen            int  // statusGen when the load started
+	Skipped        bool // an operation was running, so nothing was loaded
+	HasCheckpoints bool
+	HasChanges     bool
+	OriginBranch   string
+	Status         *models.Status
+}
+
+// loadMenuStatus reads what the main menu shows from git
+func loadMenuStatus(repo *git.Repo) statusLoadedMsg {
+	msg := statusLoadedMsg{
+		HasCheckpoints: repo.HasCheckpoints(),
+		HasChanges:     repo.HasChangesInScope(),
+		OriginBranch:   repo.GetOriginBranch(),
+	}
+	msg.Status, _ = repo.GetHeaderStatus()
+	return msg
+}
+
 // updateDisabledItems updates which menu items should be disabled
 func (a *App) updateDisabledItems() {
-	hasCheckpoints := git.HasCheckpoints()
-	hasChanges := git.HasUncommittedChanges()
+	a.statusGen++ // a background refresh started before this is now stale
+	a.applyMenuStatus(loadMenuStatus(a.repo))
+}
+
+// applyMenuStatus updates the header and which menu items are disabled
+func (a *App) applyMenuStatus(msg statusLoadedMsg) {
+	hasCheckpoints, hasChanges := msg.HasCheckpoints, msg.HasChanges
+	a.OriginBranch = msg.OriginBranch
+	a.Status = msg.Status
 	
 	for i, choice := range a.MenuChoices {
 		switch choice {
@@ -85,6 +138,14 @@ func (a *App) updateDisabledItems() {
 		case "Finalize and Push":
 			a.DisabledMenuItems[i] = !hasCheckpoints
 			a.DisabledReasons[i] = ""
+		case "Return to Branch":
+			if a.OriginBranch == "" {
+				a.DisabledMenuItems[i] = true
+				a.DisabledReasons[i] = "(not switched away)"
+			} else {
+				a.DisabledMenuItems[i] = false
+				a.DisabledReasons[i] = ""
+			}
 		default:
 			a.DisabledMenuItems[i] = false
 			a.DisabledReasons[i] = ""
@@ -117,21 +178,59 @@ func (a App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a.handleResult(msg)
 	case checkpointsLoadedMsg:
 		return a.handleCheckpointsLoaded(msg)
+	case finalizePlanLoadedMsg:
+		return a.handleFinalizePlanLoaded(msg)
+	case historyLoadedMsg:
+		return a.handleHistoryLoaded(msg)
+	case backupsLoadedMsg:
+		return a.handleBackupsLoaded(msg)
+	case diffLoadedMsg:
+		return a.handleDiffLoaded(msg)
+	case filesLoadedMsg:
+		return a.handleFilesLoaded(msg)
+	case tea.WindowSizeMsg:
+		a.Width, a.Height = msg.Width, msg.Height
+		return a, nil
 	case refreshMsg:
 		return a.handleRefresh(msg)
+	case statusLoadedMsg:
+		return a.handleStatusLoaded(msg)
+	case watchStartedMsg:
+		return a.handleWatchStarted(msg)
+	case watchTickMsg:
+		return a.handleWatchTick(msg)
+	case watchPolledMsg:
+		return a.handleWatchPolled(msg)
 	}
 	return a, nil
 }
 
-// handleRefresh updates disabled state and schedules next refresh
+// handleRefresh reloads the menu state in the background; the next refresh
+// is scheduled once it arrives, so loads never pile up on a slow repository
 func (a App) handleRefresh(msg refreshMsg) (tea.Model, tea.Cmd) {
 	// Only refresh when on main menu to avoid unnecessary work
-	if a.CurrentState == models.StateMenu {
-		a.updateDisabledItems()
-		// Schedule next refresh
+	if a.CurrentState != models.StateMenu {
 		return a, doRefresh()
 	}
-	// If not on main menu, still schedule next refresh but don't update
+	repo, gen := a.repo, a.statusGen
+	return a, func() tea.Msg {
+		// Leave git to a running operation and try again on the next tick
+		if !operationMu.TryLock() {
+			return statusLoadedMsg{Gen: gen, Skipped: true}
+		}
+		defer operationMu.Unlock()
+		msg := loadMenuStatus(repo)
+		msg.Gen = gen
+		return msg
+	}
+}
+
+// handleStatusLoaded applies a background refresh and schedules the next one
+func (a App) handleStatusLoaded(msg statusLoadedMsg) (tea.Model, tea.Cmd) {
+	// Skip results the user left the menu during, or that a newer load replaced
+	if a.CurrentState == models.StateMenu && msg.Gen == a.statusGen && !msg.Skipped {
+		a.applyMenuStatus(msg)
+	}
 	return a, doRefresh()
 }
 
@@ -144,12 +243,22 @@ func (a App) View() string {
 		return ui.RenderCheckpointCreation(a.AppModel)
 	case models.StateCheckpointNoteInput:
 		return ui.RenderNoteInput(a.AppModel)
+	case models.StateCheckpointFileSelection:
+		return ui.RenderFileSelection(a.AppModel)
 	case models.StateCheckpointSelection:
 		return ui.RenderCheckpointSelection(a.AppModel)
 	case models.StateFinalizeOptions:
 		return ui.RenderFinalizeOptions(a.AppModel)
 	case models.StateFinalizeMessageInput:
 		return ui.RenderFinalizeMessageInput(a.AppModel)
+	case models.StateFinalizeConfirm:
+		return ui.RenderFinalizeConfirm(a.AppModel)
+	case models.StateHistory:
+		return ui.RenderHistory(a.AppModel)
+	case models.StateBackups:
+		return ui.RenderBackups(a.AppModel)
+	case models.StateDiff:
+		return ui.RenderDiff(a.AppModel)
 	case models.StateExecuting:
 		return ui.RenderLoading(a.AppModel)
 	case models.StateResult:
@@ -160,14 +269,37 @@ func (a App) View() string {
 
 // resultMsg represents a command result
 type resultMsg struct {
-	Content string
-	IsError bool
+	Content  string
+	IsError  bool
+	Findings []models.Finding // scan warnings to show under the result
+}
+
+// Options configures how the TUI starts
+type Options struct {
+	Repo   *git.Repo // repository to work on; nil means the current directory
+	DryRun bool
+	Trace  bool
 }
 
 // RunApp starts the Bubble Tea application
 func RunApp() error {
+	return RunAppWithOptions(Options{})
+}
+
+// RunAppWithOptions starts the Bubble Tea application with the given options
+func RunAppWithOptions(opts Options) error {
+	ui.ApplyConfig()
+	repo := opts.Repo
+	if repo == nil {
+		repo = git.Open("")
+	}
+	model := InitialModel(repo)
+	model.DryRun = opts.DryRun
+	model.Trace = opts.Trace
+	model.applyRunnerOptions()
+
 	p := tea.NewProgram(
-		InitialModel(),
+		model,
 		tea.WithInput(os.Stdin),
 		tea.WithOutput(os.Stderr),
 	)
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"testing"
+	"vibe-check/internal/git"
+	"vibe-check/internal/models"
+)
+
+// menuApp returns an app on the main menu whose git commands go to a fake
+func menuApp(t *testing.T) (App, *git.FakeRunner) {
+	t.Helper()
+	fake := git.NewFakeRunner()
+	a := App{repo: &git.Repo{Runner: fake}, AppModel: models.AppModel{
+		CurrentState:      models.StateMenu,
+		MenuChoices:       MenuOptions,
+		DisabledMenuItems: make(map[int]bool),
+		DisabledReasons:   make(map[int]string),
+	}}
+	return a, fake
+}
+
+func TestRefreshLoadsOffTheUIGoroutine(t *testing.T) {
+	a, fake := menuApp(t)
+	// A dirty working tree and a shadow checkpoint: GetStatus would snapshot the tree
+	fake.Respond(git.Result{Stdout: t.TempDir()}, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	fake.Respond(git.Result{Stdout: "main"}, "rev-parse", "--abbrev-ref", "HEAD")
+	fake.Respond(git.Result{Stdout: " M a.go"}, "status", "--porcelain")
+	fake.Respond(git.Result{Stdout: "refs/vibe-check/main/1\x1f2222222\x1f2222222\x1f1\x1f" +
+		"CHECKPOINT: shadow\n\nVibe-Checkpoint: 1\nVibe-Shadow: true\x1e"}, "for-each-ref", "--sort=-refname")
+	fake.Respond(git.Result{Stdout: "2222222 1111111 3333333"}, "rev-list", "--parents")
+
+	model, cmd := a.handleRefresh(refreshMsg{})
+	if len(fake.Calls()) > 0 {
+		t.Fatalf("refresh ran git inside Update: %v", fake.Commands())
+	}
+	if cmd == nil {
+		t.Fatal("refresh returned no command")
+	}
+	if _, ok := cmd().(statusLoadedMsg); !ok {
+		t.Fatal("refresh command did not load the status")
+	}
+	if fake.Ran("add") || fake.Ran("write-tree") {
+		t.Errorf("header refresh snapshotted the working tree: %v", fake.Commands())
+	}
+
+	a = model.(App)
+	model, _ = a.handleStatusLoaded(statusLoadedMsg{Gen: a.statusGen, OriginBranch: "main"})
+	if got := model.(App).OriginBranch; got != "main" {
+		t.Errorf("status not applied, origin branch %q", got)
+	}
+}
+
+func TestStaleRefreshIsDropped(t *testing.T) {
+	a, _ := menuApp(t)
+	_, cmd := a.handleRefresh(refreshMsg{})
+	stale := cmd().(statusLoadedMsg)
+
+	a.updateDisabledItems() // e.g. after returning from an operation
+	a.OriginBranch = "feature"
+	model, _ := a.handleStatusLoaded(stale)
+	if got := model.(App).OriginBranch; got != "feature" {
+		t.Errorf("stale refresh applied, origin branch %q", got)
+	}
+}
+
+func TestRefreshSkippedDuringOperation(t *testing.T) {
+	a, fake := menuApp(t)
+	_, cmd := a.handleRefresh(refreshMsg{})
+
+	operationMu.Lock()
+	msg := cmd().(statusLoadedMsg)
+	operationMu.Unlock()
+
+	if !msg.Skipped || len(fake.Calls()) > 0 {
+		t.Fatalf("refresh ran during an operation: %v", fake.Commands())
+	}
+	a.OriginBranch = "feature"
+	model, next := a.handleStatusLoaded(msg)
+	if got := model.(App).OriginBranch; got != "feature" {
+		t.Errorf("skipped refresh applied, origin branch %q", got)
+	}
+	if next == nil {
+		t.Error("no refresh scheduled after a skipped one")
+	}
+}
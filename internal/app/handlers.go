@@ -1,10 +1,13 @@
 package app
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"vibe-check/internal/config"
 	"vibe-check/internal/git"
 	"vibe-check/internal/models"
+	"vibe-check/internal/ui"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -18,12 +21,22 @@ func (a App) handleKeyPress(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return a.handleCheckpointCreationKeys(msg)
 	case models.StateCheckpointNoteInput:
 		return a.handleNoteInputKeys(msg)
+	case models.StateCheckpointFileSelection:
+		return a.handleFileSelectionKeys(msg)
 	case models.StateCheckpointSelection:
 		return a.handleCheckpointSelectionKeys(msg)
 	case models.StateFinalizeOptions:
 		return a.handleFinalizeOptionsKeys(msg)
 	case models.StateFinalizeMessageInput:
 		return a.handleFinalizeMessageInputKeys(msg)
+	case models.StateFinalizeConfirm:
+		return a.handleFinalizeConfirmKeys(msg)
+	case models.StateHistory:
+		return a.handleHistoryKeys(msg)
+	case models.StateBackups:
+		return a.handleBackupsKeys(msg)
+	case models.StateDiff:
+		return a.handleDiffKeys(msg)
 	case models.StateResult:
 		return a.handleResultKeys(msg)
 	}
@@ -41,6 +54,14 @@ func (a App) handleMenuKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		a.moveCursorDown()
 	case "enter", " ":
 		return a.executeMenuAction()
+	case "d":
+		a.DryRun = !a.DryRun
+		a.applyRunnerOptions()
+	case "t":
+		a.Trace = !a.Trace
+		a.applyRunnerOptions()
+	case "a":
+		return a.toggleAutoCheckpoint()
 	}
 	return a, nil
 }
@@ -108,6 +129,18 @@ func (a App) executeMenuAction() (tea.Model, tea.Cmd) {
 		a.CurrentState = models.StateFinalizeOptions
 		a.FinalizeOptionsCursor = 0
 		return a, nil
+	case strings.HasPrefix(selected, "Return"):
+		return a.returnToOriginBranch()
+	case strings.HasPrefix(selected, "History"):
+		a.CurrentState = models.StateExecuting
+		a.Loading = true
+		a.LoadingText = "Loading history..."
+		return a.loadHistory()
+	case strings.HasPrefix(selected, "Backups"):
+		a.CurrentState = models.StateExecuting
+		a.Loading = true
+		a.LoadingText = "Loading backups..."
+		return a.loadBackups()
 	case strings.HasPrefix(selected, "Exit"):
 		return a, tea.Quit
 	}
@@ -144,7 +177,15 @@ func (a App) executeCheckpointAction() (tea.Model, tea.Cmd) {
 	case strings.HasPrefix(selected, "Create Checkpoint with"):
 		a.CurrentState = models.StateCheckpointNoteInput
 		a.CustomNote = ""
+		a.PendingPaths = nil
 		return a, nil
+	case strings.HasPrefix(selected, "Create Checkpoint from"):
+		a.CurrentState = models.StateExecuting
+		a.Loading = true
+		a.LoadingText = "Loading changed files..."
+		return a.loadFiles()
+	case strings.HasPrefix(selected, "Create Shadow"):
+		return a.createCheckpointWithOptions(git.CheckpointOptions{Shadow: true})
 	case strings.HasPrefix(selected, "Create Checkpoint"):
 		return a.createCheckpoint("")
 	case strings.HasPrefix(selected, "Back"):
@@ -160,24 +201,113 @@ func (a App) executeCheckpointAction() (tea.Model, tea.Cmd) {
 func (a App) handleNoteInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
 	case "ctrl+c", "q", "esc":
+		// A note for selected files goes back to the file picker
 		a.CurrentState = models.StateCheckpointCreation
+		if a.PendingPaths != nil {
+			a.CurrentState = models.StateCheckpointFileSelection
+		}
 		a.CustomNote = ""
+		a.PendingPaths = nil
 		return a, nil
 	case "enter":
-		return a.createCheckpoint(a.CustomNote)
+		return a.createCheckpointWithOptions(git.CheckpointOptions{Note: a.CustomNote, Paths: a.PendingPaths})
 	case "backspace":
 		if len(a.CustomNote) > 0 {
 			a.CustomNote = a.CustomNote[:len(a.CustomNote)-1]
 		}
 	default:
 		// Add character to note
-		if len(msg.String()) == 1 && len(a.CustomNote) < 50 {
+		if len(msg.String()) == 1 && len(a.CustomNote) < config.Int("ui.note_limit") {
 			a.CustomNote += msg.String()
 		}
 	}
 	return a, nil
 }
 
+// filesLoadedMsg carries the uncommitted files offered by the file picker
+type filesLoadedMsg struct {
+	Files []string
+}
+
+// loadFiles lists the uncommitted files for the file picker
+func (a App) loadFiles() (tea.Model, tea.Cmd) {
+	return a, func() tea.Msg {
+		files, err := a.repo.ChangedFiles()
+		if err != nil {
+			return resultMsg{
+				Content: "Error loading changed files: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return filesLoadedMsg{Files: files}
+	}
+}
+
+// handleFilesLoaded opens the file picker with nothing selected
+func (a App) handleFilesLoaded(msg filesLoadedMsg) (tea.Model, tea.Cmd) {
+	a.Loading = false
+	a.CurrentState = models.StateCheckpointFileSelection
+	a.Files = msg.Files
+	a.FileSelected = make([]bool, len(msg.Files))
+	a.FileCursor = 0
+	return a, nil
+}
+
+// handleFileSelectionKeys processes keys in the file picker
+func (a App) handleFileSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		a.CurrentState = models.StateCheckpointCreation
+		return a, nil
+	case "up", "k":
+		if a.FileCursor > 0 {
+			a.FileCursor--
+		}
+	case "down", "j":
+		if a.FileCursor < len(a.Files)-1 {
+			a.FileCursor++
+		}
+	case " ", "x":
+		if len(a.Files) > 0 {
+			a.FileSelected[a.FileCursor] = !a.FileSelected[a.FileCursor]
+		}
+	case "a":
+		// Select all, or clear the selection when everything is selected
+		all := true
+		for _, selected := range a.FileSelected {
+			all = all && selected
+		}
+		for i := range a.FileSelected {
+			a.FileSelected[i] = !all
+		}
+	case "enter":
+		if paths := a.selectedPaths(); paths != nil {
+			return a.createCheckpointWithOptions(git.CheckpointOptions{Paths: paths})
+		}
+	case "n":
+		if paths := a.selectedPaths(); paths != nil {
+			a.CurrentState = models.StateCheckpointNoteInput
+			a.CustomNote = ""
+			a.PendingPaths = paths
+		}
+	}
+	return a, nil
+}
+
+// selectedPaths returns pathspecs for the files selected in the picker, or
+// nil when none is. Status paths are relative to the repository root, so
+// they are anchored there, and matched literally in case a name holds a '*'.
+func (a App) selectedPaths() []string {
+	var paths []string
+	for i, file := range a.Files {
+		if a.FileSelected[i] {
+			paths = append(paths, ":(top,literal)"+file)
+		}
+	}
+	return paths
+}
+
 // handleCheckpointSelectionKeys processes keys in checkpoint selection
 func (a App) handleCheckpointSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	switch msg.String() {
@@ -199,6 +329,102 @@ func (a App) handleCheckpointSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 			selected := a.Checkpoints[a.CheckpointCursor]
 			return a.switchToCheckpoint(selected.Hash)
 		}
+	case "d":
+		if len(a.Checkpoints) > 0 {
+			// Compare with the checkpoint before it, or its parent commit for the oldest entry
+			selected := a.Checkpoints[a.CheckpointCursor]
+			from := ""
+			if a.CheckpointCursor+1 < len(a.Checkpoints) {
+				from = a.Checkpoints[a.CheckpointCursor+1].Hash
+			}
+			return a.loadDiff(from, selected.Hash)
+		}
+	case "w":
+		if len(a.Checkpoints) > 0 {
+			return a.loadDiff(a.Checkpoints[a.CheckpointCursor].Hash, "")
+		}
+	case "g":
+		a.GroupByDay = !a.GroupByDay
+	}
+	return a, nil
+}
+
+// diffLoadedMsg carries the diff shown in the diff viewer
+type diffLoadedMsg struct {
+	Diff *models.Diff
+}
+
+// loadDiff computes the diff between two checkpoints; an empty from means the
+// parent of to, and an empty to means the working tree
+func (a App) loadDiff(from, to string) (tea.Model, tea.Cmd) {
+	return a, func() tea.Msg {
+		if from == "" {
+			parent, err := a.repo.ParentRev(to)
+			if err != nil {
+				return resultMsg{
+					Content: "Error loading diff: " + err.Error(),
+					IsError: true,
+				}
+			}
+			from = parent
+		}
+		
+		diff, err := a.repo.DiffCheckpoints(from, to, git.DiffPatch)
+		if err != nil {
+			return resultMsg{
+				Content: "Error loading diff: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return diffLoadedMsg{Diff: diff}
+	}
+}
+
+// handleDiffLoaded opens the diff viewer
+func (a App) handleDiffLoaded(msg diffLoadedMsg) (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateDiff
+	a.DiffTitle = msg.Diff.From + " → " + msg.Diff.To
+	a.DiffLines = strings.Split(msg.Diff.Output, "\n")
+	if msg.Diff.Output == "" {
+		a.DiffLines = nil
+	}
+	a.DiffOffset = 0
+	return a, nil
+}
+
+// handleDiffKeys scrolls the diff viewer
+func (a App) handleDiffKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	page := ui.DiffPageSize(a.AppModel)
+	maxOffset := len(a.DiffLines) - page
+	if maxOffset < 0 {
+		maxOffset = 0
+	}
+	
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		a.CurrentState = models.StateCheckpointSelection
+		a.DiffLines = nil
+		return a, nil
+	case "up", "k":
+		a.DiffOffset--
+	case "down", "j":
+		a.DiffOffset++
+	case "pgup", "b":
+		a.DiffOffset -= page
+	case "pgdown", "f", " ":
+		a.DiffOffset += page
+	case "home", "g":
+		a.DiffOffset = 0
+	case "end", "G":
+		a.DiffOffset = maxOffset
+	}
+	
+	if a.DiffOffset > maxOffset {
+		a.DiffOffset = maxOffset
+	}
+	if a.DiffOffset < 0 {
+		a.DiffOffset = 0
 	}
 	return a, nil
 }
@@ -207,6 +433,7 @@ func (a App) handleCheckpointSelectionKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 func (a App) handleResultKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	a.CurrentState = models.StateMenu
 	a.Result = ""
+	a.Findings = nil
 	a.updateDisabledItems() // Update disabled items when returning to menu
 	a.moveToFirstEnabledItem()
 	return a, nil
@@ -218,6 +445,7 @@ func (a App) handleResult(msg resultMsg) (tea.Model, tea.Cmd) {
 	a.CurrentState = models.StateResult
 	a.Result = msg.Content
 	a.IsError = msg.IsError
+	a.Findings = msg.Findings
 	return a, nil
 }
 
@@ -231,8 +459,13 @@ func (a App) showPlaceholder(message string) (tea.Model, tea.Cmd) {
 
 // createCheckpoint creates a git checkpoint
 func (a App) createCheckpoint(customNote string) (tea.Model, tea.Cmd) {
-	return a, func() tea.Msg {
-		err := git.CreateCheckpoint(customNote)
+	return a.createCheckpointWithOptions(git.CheckpointOptions{Note: customNote})
+}
+
+// createCheckpointWithOptions creates a git checkpoint using the given options
+func (a App) createCheckpointWithOptions(opts git.CheckpointOptions) (tea.Model, tea.Cmd) {
+	return a, runOperation(func() resultMsg {
+		cp, err := a.repo.CreateCheckpointWithOptions(opts)
 		if err != nil {
 			return resultMsg{
 				Content: "Error creating checkpoint: " + err.Error(),
@@ -241,21 +474,28 @@ func (a App) createCheckpoint(customNote string) (tea.Model, tea.Cmd) {
 		}
 		
 		message := "Checkpoint created successfully"
-		if customNote != "" {
-			message += " with note: \"" + customNote + "\""
+		if opts.Shadow {
+			message = "Checkpoint created successfully as a shadow snapshot (branch untouched)"
+		}
+		if len(opts.Paths) > 0 {
+			message = "Checkpoint created successfully from the selected files (other changes left in place)"
+		}
+		if opts.Note != "" {
+			message += " with note: \"" + opts.Note + "\""
 		}
 		
 		return resultMsg{
-			Content: message,
-			IsError: false,
+			Content:  message,
+			IsError:  false,
+			Findings: cp.Findings,
 		}
-	}
+	})
 }
 
 // loadCheckpoints loads checkpoints for selection
 func (a App) loadCheckpoints() (tea.Model, tea.Cmd) {
 	return a, func() tea.Msg {
-		checkpoints, err := git.GetCheckpointsFromReflog()
+		checkpoints, err := a.repo.GetCheckpoints()
 		if err != nil {
 			return resultMsg{
 				Content: "Error loading checkpoints: " + err.Error(),
@@ -263,16 +503,44 @@ func (a App) loadCheckpoints() (tea.Model, tea.Cmd) {
 			}
 		}
 		
+		if err := a.repo.LoadCheckpointDetails(checkpoints); err != nil {
+			return resultMsg{
+				Content: "Error loading checkpoints: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		current, _ := a.repo.GetCurrentCheckpointHash()
+		
 		return checkpointsLoadedMsg{
 			Checkpoints: checkpoints,
+			Current:     current,
 		}
 	}
 }
 
+// returnToOriginBranch checks out the branch the last switch started from
+func (a App) returnToOriginBranch() (tea.Model, tea.Cmd) {
+	return a, runOperation(func() resultMsg {
+		branch, err := a.repo.ReturnToOriginBranch()
+		if err != nil {
+			return resultMsg{
+				Content: "Error returning to branch: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return resultMsg{
+			Content: "Returned to branch: " + branch,
+			IsError: false,
+		}
+	})
+}
+
 // switchToCheckpoint switches to a specific checkpoint
 func (a App) switchToCheckpoint(hash string) (tea.Model, tea.Cmd) {
-	return a, func() tea.Msg {
-		err := git.SwitchToCheckpoint(hash)
+	return a, runOperation(func() resultMsg {
+		err := a.repo.SwitchToCheckpoint(hash)
 		if err != nil {
 			return resultMsg{
 				Content: "Error switching to checkpoint: " + err.Error(),
@@ -284,12 +552,13 @@ func (a App) switchToCheckpoint(hash string) (tea.Model, tea.Cmd) {
 			Content: "Switched to checkpoint: " + hash,
 			IsError: false,
 		}
-	}
+	})
 }
 
 // checkpointsLoadedMsg represents loaded checkpoints
 type checkpointsLoadedMsg struct {
 	Checkpoints []models.Checkpoint
+	Current     string
 }
 
 // handleCheckpointsLoaded handles loaded checkpoints message
@@ -297,6 +566,7 @@ func (a App) handleCheckpointsLoaded(msg checkpointsLoadedMsg) (tea.Model, tea.C
 	a.Loading = false
 	a.CurrentState = models.StateCheckpointSelection
 	a.Checkpoints = msg.Checkpoints
+	a.CurrentCheckpoint = msg.Current
 	a.CheckpointCursor = 0
 	return a, nil
 }
@@ -312,9 +582,9 @@ func (a App) finalizeAndPushWithMessage(customMessage string) (tea.Model, tea.Cm
 	a.Loading = true
 	a.LoadingText = "Finalizing and pushing..."
 	
-	return a, func() tea.Msg {
+	return a, runOperation(func() resultMsg {
 		// Proceed with finalize and push
-		err := git.FinalizeAndPushWithMessage(customMessage)
+		plan, err := a.repo.FinalizeAndPushWithMessage(customMessage)
 		if err != nil {
 			return resultMsg{
 				Content: "Error during finalize and push: " + err.Error(),
@@ -330,10 +600,11 @@ func (a App) finalizeAndPushWithMessage(customMessage string) (tea.Model, tea.Cm
 		}
 
 		return resultMsg{
-			Content: successMessage,
-			IsError: false,
+			Content:  successMessage,
+			IsError:  false,
+			Findings: plan.Findings,
 		}
-	}
+	})
 }
 
 
@@ -367,9 +638,14 @@ func (a App) executeFinalizeAction() (tea.Model, tea.Cmd) {
 	case strings.HasPrefix(selected, "Finalize and Push with Custom"):
 		a.CurrentState = models.StateFinalizeMessageInput
 		a.CustomCommitMessage = ""
+		a.PendingNoPush = false
 		return a, nil
 	case strings.HasPrefix(selected, "Finalize and Push (Auto"):
-		return a.finalizeAndPushWithMessage("")
+		a.PendingNoPush = false
+		return a.loadFinalizePlan("")
+	case strings.HasPrefix(selected, "Finalize Locally"):
+		a.PendingNoPush = true
+		return a.loadFinalizePlan("")
 	case strings.HasPrefix(selected, "Back"):
 		a.CurrentState = models.StateMenu
 		a.updateDisabledItems()
@@ -387,7 +663,7 @@ func (a App) handleFinalizeMessageInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		return a, nil
 	case "enter":
 		if len(a.CustomCommitMessage) > 0 {
-			return a.finalizeAndPushWithMessage(a.CustomCommitMessage)
+			return a.loadFinalizePlan(a.CustomCommitMessage)
 		}
 		return a, nil
 	case "backspace":
@@ -396,9 +672,341 @@ func (a App) handleFinalizeMessageInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd)
 		}
 	default:
 		// Add character to message if it's printable and under limit
-		if len(msg.String()) == 1 && len(a.CustomCommitMessage) < 100 {
+		if len(msg.String()) == 1 && len(a.CustomCommitMessage) < config.Int("ui.message_limit") {
 			a.CustomCommitMessage += msg.String()
 		}
 	}
 	return a, nil
-}
\ No newline at end of file
+}
+// finalizePlanLoadedMsg carries the plan shown on the confirmation screen
+type finalizePlanLoadedMsg struct {
+	Plan    *models.FinalizePlan
+	Message string
+}
+
+// loadFinalizePlan computes what finalize would do before asking for confirmation
+func (a App) loadFinalizePlan(customMessage string) (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateExecuting
+	a.Loading = true
+	a.LoadingText = "Preparing finalize plan..."
+
+	return a, func() tea.Msg {
+		plan, err := a.repo.GetFinalizePlan()
+		if err != nil {
+			return resultMsg{
+				Content: "Cannot finalize: " + err.Error(),
+				IsError: true,
+			}
+		}
+
+		return finalizePlanLoadedMsg{
+			Plan:    plan,
+			Message: customMessage,
+		}
+	}
+}
+
+// handleFinalizePlanLoaded shows the mandatory confirmation screen
+func (a App) handleFinalizePlanLoaded(msg finalizePlanLoadedMsg) (tea.Model, tea.Cmd) {
+	a.Loading = false
+	a.CurrentState = models.StateFinalizeConfirm
+	a.FinalizePlan = msg.Plan
+	a.PendingCommitMessage = msg.Message
+	a.ConfirmCursor = 0
+	return a, nil
+}
+
+// handleFinalizeConfirmKeys processes keys on the finalize confirmation screen
+func (a App) handleFinalizeConfirmKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q", "esc", "n":
+		return a.cancelFinalize()
+	case "up", "k":
+		if a.ConfirmCursor > 0 {
+			a.ConfirmCursor--
+		}
+	case "down", "j":
+		if a.ConfirmCursor < len(a.ConfirmOptions)-1 {
+			a.ConfirmCursor++
+		}
+	case "y":
+		return a.confirmFinalize()
+	case "enter", " ":
+		if strings.HasPrefix(a.ConfirmOptions[a.ConfirmCursor], "Confirm") {
+			return a.confirmFinalize()
+		}
+		return a.cancelFinalize()
+	}
+	return a, nil
+}
+
+// confirmFinalize runs the confirmed finalize, pushing unless finalizing locally
+func (a App) confirmFinalize() (tea.Model, tea.Cmd) {
+	if a.PendingNoPush {
+		return a.finalizeLocally(a.PendingCommitMessage)
+	}
+	return a.finalizeAndPushWithMessage(a.PendingCommitMessage)
+}
+
+// finalizeLocally squashes checkpoints into a local commit without pushing
+func (a App) finalizeLocally(customMessage string) (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateExecuting
+	a.Loading = true
+	a.LoadingText = "Finalizing locally..."
+	
+	return a, runOperation(func() resultMsg {
+		if _, err := a.repo.SquashCheckpoints(customMessage); err != nil {
+			return resultMsg{
+				Content: "Error during local finalize: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return resultMsg{
+			Content: "Successfully finalized locally! Run `vibe-check push` when you are ready to publish.",
+			IsError: false,
+		}
+	})
+}
+
+// cancelFinalize leaves the confirmation screen without changing anything
+func (a App) cancelFinalize() (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateFinalizeOptions
+	a.FinalizePlan = nil
+	a.PendingCommitMessage = ""
+	a.PendingNoPush = false
+	return a, nil
+}
+
+// historyLoadedMsg carries the operation journal shown on the history screen
+type historyLoadedMsg struct {
+	History []models.Operation
+}
+
+// loadHistory reads the operation journal
+func (a App) loadHistory() (tea.Model, tea.Cmd) {
+	return a, func() tea.Msg {
+		history, err := a.repo.GetHistory()
+		if err != nil {
+			return resultMsg{
+				Content: "Error loading history: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return historyLoadedMsg{History: history}
+	}
+}
+
+// handleHistoryLoaded shows the history screen
+func (a App) handleHistoryLoaded(msg historyLoadedMsg) (tea.Model, tea.Cmd) {
+	a.Loading = false
+	a.CurrentState = models.StateHistory
+	a.History = msg.History
+	a.HistoryCursor = 0
+	return a, nil
+}
+
+// handleHistoryKeys processes keys on the history screen
+func (a App) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		a.CurrentState = models.StateMenu
+		a.updateDisabledItems()
+		a.moveToFirstEnabledItem()
+		return a, nil
+	case "up", "k":
+		if a.HistoryCursor > 0 {
+			a.HistoryCursor--
+		}
+	case "down", "j":
+		if a.HistoryCursor < len(a.History)-1 {
+			a.HistoryCursor++
+		}
+	case "u":
+		return a.undo()
+	case "r":
+		return a.redo()
+	}
+	return a, nil
+}
+
+// undo reverts the most recent operation
+func (a App) undo() (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateExecuting
+	a.Loading = true
+	a.LoadingText = "Undoing..."
+	
+	return a, runOperation(func() resultMsg {
+		op, err := a.repo.Undo()
+		if err != nil {
+			return resultMsg{
+				Content: "Error during undo: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		content := "Undid: " + op.Summary
+		if op.Kind == git.OpFinalize {
+			content += "\nPushes are not undone - if the finalized commit was pushed, the remote still has it."
+		}
+		return resultMsg{
+			Content: content,
+			IsError: false,
+		}
+	})
+}
+
+// redo replays the most recently undone operation
+func (a App) redo() (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateExecuting
+	a.Loading = true
+	a.LoadingText = "Redoing..."
+	
+	return a, runOperation(func() resultMsg {
+		op, err := a.repo.Redo()
+		if err != nil {
+			return resultMsg{
+				Content: "Error during redo: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return resultMsg{
+			Content: "Redid: " + op.Summary,
+			IsError: false,
+		}
+	})
+}
+
+// backupsLoadedMsg carries the backups shown on the backups screen
+type backupsLoadedMsg struct {
+	Backups []models.Backup
+}
+
+// loadBackups lists the backup branches
+func (a App) loadBackups() (tea.Model, tea.Cmd) {
+	return a, func() tea.Msg {
+		backups, err := a.repo.ListBackups()
+		if err != nil {
+			return resultMsg{
+				Content: "Error loading backups: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return backupsLoadedMsg{Backups: backups}
+	}
+}
+
+// handleBackupsLoaded shows the backups screen
+func (a App) handleBackupsLoaded(msg backupsLoadedMsg) (tea.Model, tea.Cmd) {
+	a.Loading = false
+	a.CurrentState = models.StateBackups
+	a.Backups = msg.Backups
+	a.BackupCursor = 0
+	return a, nil
+}
+
+// handleBackupsKeys processes keys on the backups screen
+func (a App) handleBackupsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q", "esc":
+		a.CurrentState = models.StateMenu
+		a.updateDisabledItems()
+		a.moveToFirstEnabledItem()
+		return a, nil
+	case "up", "k":
+		if a.BackupCursor > 0 {
+			a.BackupCursor--
+		}
+	case "down", "j":
+		if a.BackupCursor < len(a.Backups)-1 {
+			a.BackupCursor++
+		}
+	case "enter", "r":
+		if len(a.Backups) > 0 {
+			return a.restoreBackup(a.Backups[a.BackupCursor].Name, false)
+		}
+	case "R":
+		if len(a.Backups) > 0 {
+			return a.restoreBackup(a.Backups[a.BackupCursor].Name, true)
+		}
+	case "d":
+		if len(a.Backups) > 0 {
+			return a.diffBackup(a.Backups[a.BackupCursor].Name)
+		}
+	case "p":
+		return a.pruneBackups()
+	}
+	return a, nil
+}
+
+// restoreBackup restores a backup onto its original branch. With force it
+// drops commits made on the branch since the backup.
+func (a App) restoreBackup(name string, force bool) (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateExecuting
+	a.Loading = true
+	a.LoadingText = "Restoring backup..."
+	
+	return a, runOperation(func() resultMsg {
+		backup, err := a.repo.RestoreBackup(name, force)
+		if errors.Is(err, git.ErrBackupBehind) {
+			err = fmt.Errorf("%w\nPress R on the backup to drop them", err)
+		}
+		if err != nil {
+			return resultMsg{
+				Content: "Error restoring backup: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return resultMsg{
+			Content: fmt.Sprintf("Restored %s onto branch %s", backup.Name, backup.Branch),
+			IsError: false,
+		}
+	})
+}
+
+// diffBackup shows a diffstat between HEAD and a backup
+func (a App) diffBackup(name string) (tea.Model, tea.Cmd) {
+	return a, runOperation(func() resultMsg {
+		diff, err := a.repo.DiffBackup(name, true)
+		if err != nil {
+			return resultMsg{
+				Content: "Error comparing with backup: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		if diff == "" {
+			diff = "No differences"
+		}
+		return resultMsg{
+			Content: "HEAD vs " + name + ":\n" + diff,
+			IsError: false,
+		}
+	})
+}
+
+// pruneBackups deletes backups older than a week
+func (a App) pruneBackups() (tea.Model, tea.Cmd) {
+	a.CurrentState = models.StateExecuting
+	a.Loading = true
+	a.LoadingText = "Pruning backups..."
+	
+	return a, runOperation(func() resultMsg {
+		pruned, err := a.repo.PruneBackups(config.Duration("backups.prune_age"))
+		if err != nil {
+			return resultMsg{
+				Content: "Error pruning backups: " + err.Error(),
+				IsError: true,
+			}
+		}
+		
+		return resultMsg{
+			Content: fmt.Sprintf("Pruned %d backup(s) older than %s", len(pruned), config.String("backups.prune_age")),
+			IsError: false,
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"strings"
+	"sync"
+	"vibe-check/internal/git"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// session collects what git did during the current operation so the result
+// view can show the dry-run plan and trace. The TUI renders to the terminal,
+// so trace lines cannot be written to stderr directly.
+var session = &operationSession{}
+
+type operationSession struct {
+	mu     sync.Mutex
+	dryRun *git.DryRunRunner
+	trace  []string
+}
+
+// applyRunnerOptions installs the git runner matching the dry-run/trace toggles
+func (a *App) applyRunnerOptions() {
+	opts := git.RunnerOptions{DryRun: a.DryRun}
+	if a.Trace {
+		opts.Trace = session.log
+	}
+	// A copy, so commands still running on the old runner are not affected
+	repo := *a.repo
+	var dryRun *git.DryRunRunner
+	repo.Runner, dryRun = git.NewRunner(opts)
+	a.repo = &repo
+
+	session.mu.Lock()
+	session.dryRun = dryRun
+	session.trace = nil
+	session.mu.Unlock()
+}
+
+func (s *operationSession) log(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trace = append(s.trace, line)
+}
+
+// begin clears the plan and trace recorded by previous operations
+func (s *operationSession) begin() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trace = nil
+	if s.dryRun != nil {
+		s.dryRun.Reset()
+	}
+}
+
+// annotate appends the dry-run plan and trace of the finished operation to its result
+func (s *operationSession) annotate(msg resultMsg) resultMsg {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var extra strings.Builder
+	if s.dryRun != nil {
+		extra.WriteString("\n\nDry run - nothing was changed. Planned git commands:\n")
+		extra.WriteString(git.FormatPlan(s.dryRun.Plan()))
+	}
+	if len(s.trace) > 0 {
+		extra.WriteString("\n\nGit commands run:\n")
+		extra.WriteString(strings.Join(s.trace, "\n"))
+	}
+	msg.Content += extra.String()
+	return msg
+}
+
+// operationMu serializes changes to the repository, so an auto-checkpoint
+// poll never commits in the middle of a finalize or switch
+var operationMu sync.Mutex
+
+// runOperation wraps a mutating operation so its result carries the plan and trace
+func runOperation(op func() resultMsg) tea.Cmd {
+	return func() tea.Msg {
+		operationMu.Lock()
+		defer operationMu.Unlock()
+		session.begin()
+		return session.annotate(op())
+	}
+}
@@ -0,0 +1,124 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"vibe-check/internal/config"
+	"vibe-check/internal/git"
+	"vibe-check/internal/models"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// The auto-checkpoint toggle runs a git.Watcher from the TUI. Each message
+// carries the generation it belongs to, so ticks of a watcher that was
+// switched off are dropped.
+
+// watchStartedMsg carries the watcher created when auto-checkpoint is switched on
+type watchStartedMsg struct {
+	Gen     int
+	Watcher *git.Watcher
+	Err     error
+}
+
+// watchTickMsg asks for the next poll of the working tree
+type watchTickMsg struct {
+	Gen  int
+	Time time.Time
+}
+
+// watchPolledMsg carries the result of one poll
+type watchPolledMsg struct {
+	Gen        int
+	Checkpoint *models.Checkpoint
+	Err        error
+}
+
+// toggleAutoCheckpoint switches the watcher on or off
+func (a App) toggleAutoCheckpoint() (tea.Model, tea.Cmd) {
+	a.watchGen++
+	a.watcher = nil
+	if a.AutoCheckpoint {
+		a.AutoCheckpoint = false
+		a.AutoCheckpointStatus = ""
+		return a, nil
+	}
+
+	a.AutoCheckpoint, a.AutoCheckpoints = true, 0
+	a.AutoCheckpointStatus = "starting…"
+	gen := a.watchGen
+	return a, func() tea.Msg {
+		w, err := a.repo.NewWatcher(git.WatchOptionsFromConfig())
+		return watchStartedMsg{Gen: gen, Watcher: w, Err: err}
+	}
+}
+
+// watchTick schedules the next poll
+func watchTick(gen int) tea.Cmd {
+	return tea.Tick(config.Duration("watch.poll_interval"), func(t time.Time) tea.Msg {
+		return watchTickMsg{Gen: gen, Time: t}
+	})
+}
+
+// handleWatchStarted starts polling with the new watcher
+func (a App) handleWatchStarted(msg watchStartedMsg) (tea.Model, tea.Cmd) {
+	if msg.Gen != a.watchGen {
+		return a, nil
+	}
+	if msg.Err != nil {
+		a.AutoCheckpoint = false
+		a.AutoCheckpointStatus = "failed: " + msg.Err.Error()
+		return a, nil
+	}
+	a.watcher = msg.Watcher
+	a.AutoCheckpointStatus = "watching for changes"
+	return a, watchTick(msg.Gen)
+}
+
+// handleWatchTick polls the working tree unless an operation is running
+func (a App) handleWatchTick(msg watchTickMsg) (tea.Model, tea.Cmd) {
+	if msg.Gen != a.watchGen || a.watcher == nil {
+		return a, nil
+	}
+	// Never checkpoint in the middle of an operation, or while only planning
+	if a.Loading || a.DryRun {
+		return a, watchTick(msg.Gen)
+	}
+
+	w, gen := a.watcher, msg.Gen
+	return a, func() tea.Msg {
+		// An operation started since the tick fired - skip this poll
+		if !operationMu.TryLock() {
+			return watchPolledMsg{Gen: gen}
+		}
+		defer operationMu.Unlock()
+		cp, err := w.Poll(msg.Time)
+		return watchPolledMsg{Gen: gen, Checkpoint: cp, Err: err}
+	}
+}
+
+// handleWatchPolled reports a checkpoint the watcher made and schedules the next poll
+func (a App) handleWatchPolled(msg watchPolledMsg) (tea.Model, tea.Cmd) {
+	if msg.Gen != a.watchGen || a.watcher == nil {
+		return a, nil
+	}
+
+	switch {
+	case errors.Is(msg.Err, git.ErrWatchLimit):
+		a.AutoCheckpoint, a.watcher = false, nil
+		a.AutoCheckpointStatus = fmt.Sprintf("stopped after %d checkpoint(s) (watch.max_count)", a.AutoCheckpoints)
+		return a, nil
+	case msg.Err != nil:
+		a.AutoCheckpoint, a.watcher = false, nil
+		a.AutoCheckpointStatus = "stopped: " + msg.Err.Error()
+		return a, nil
+	case msg.Checkpoint != nil:
+		a.AutoCheckpoints = a.watcher.Count()
+		a.AutoCheckpointStatus = fmt.Sprintf("%d made, last [%s] at %s", a.AutoCheckpoints, msg.Checkpoint.Hash, time.Now().Format("15:04:05"))
+		if a.CurrentState == models.StateMenu {
+			a.updateDisabledItems()
+		}
+	}
+	return a, watchTick(msg.Gen)
+}
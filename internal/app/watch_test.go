@@ -0,0 +1,37 @@
+package app
+
+import (
+	"testing"
+	"time"
+	"vibe-check/internal/git"
+)
+
+func TestWatchPollSkippedDuringOperation(t *testing.T) {
+	fake := git.NewFakeRunner()
+	fake.Respond(git.Result{Stdout: t.TempDir()}, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	repo := &git.Repo{Runner: fake}
+	watcher, err := repo.NewWatcher(git.WatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := len(fake.Calls())
+
+	a := App{repo: repo, watcher: watcher, watchGen: 1}
+	_, cmd := a.handleWatchTick(watchTickMsg{Gen: 1, Time: time.Now()})
+	if cmd == nil {
+		t.Fatal("tick did not schedule a poll")
+	}
+
+	// The tick fired before the operation started; the poll runs after
+	operationMu.Lock()
+	msg := cmd()
+	operationMu.Unlock()
+
+	polled, ok := msg.(watchPolledMsg)
+	if !ok {
+		t.Fatalf("got %T, want watchPolledMsg", msg)
+	}
+	if commands := fake.Commands()[started:]; len(commands) > 0 || polled.Checkpoint != nil || polled.Err != nil {
+		t.Errorf("poll ran during an operation: %+v %v", polled, commands)
+	}
+}
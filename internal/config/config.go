@@ -0,0 +1,361 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Settings are read from these layers, later ones taking precedence:
+//
+//	defaults
+//	vibe-check.* git config (the keys older versions used)
+//	~/.config/vibe-check/config.json
+//	.vibecheck.json at the repository root
+//	VIBE_CHECK_* environment variables, e.g. VIBE_CHECK_UI_NOTE_LIMIT=80
+//	-c key=value and the dedicated command-line flags
+//
+// Files hold nested JSON objects: {"ui": {"note_limit": 80}} sets ui.note_limit.
+
+// Source names the layer a value came from
+type Source string
+
+const (
+	SourceDefault   Source = "default"
+	SourceGitConfig Source = "git config"
+	SourceGlobal    Source = "global"
+	SourceRepo      Source = "repo"
+	SourceEnv       Source = "env"
+	SourceFlag      Source = "flag"
+)
+
+// RepoFileName is the per-repository settings file, kept at the repository root
+const RepoFileName = ".vibecheck.json"
+
+// envPrefix starts the environment variable of every setting
+const envPrefix = "VIBE_CHECK_"
+
+// Value is the effective value of a setting and where it came from
+type Value struct {
+	Key    Key
+	Value  string
+	Source Source
+	Path   string // file the value was read from, for file layers
+}
+
+// Error describes an invalid setting
+type Error struct {
+	Path string // file or variable holding the setting; empty for flags
+	Key  string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("%s: %s: %v", e.Path, e.Key, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Key, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrUnknownKey is returned for settings that are not in the schema
+var ErrUnknownKey = errors.New("unknown setting. Run `vibe-check config list` to see them all")
+
+// layer is one set of values, e.g. the contents of a file
+type layer struct {
+	source Source
+	path   string
+	values map[string]string
+}
+
+var (
+	mu     sync.RWMutex
+	layers []layer               // loaded by Load, lowest precedence first
+	flags  = map[string]string{} // set from the command line
+)
+
+// Load reads every layer below the command line. repoRoot may be empty
+// outside a repository; gitValues holds the vibe-check.* git config already
+// mapped onto setting names.
+func Load(repoRoot string, gitValues map[string]string) error {
+	loaded := []layer{}
+
+	gitLayer := layer{source: SourceGitConfig, values: map[string]string{}}
+	for name, value := range gitValues {
+		if err := validate(name, value); err != nil {
+			return &Error{Path: "git config", Key: name, Err: err}
+		}
+		gitLayer.values[name] = value
+	}
+	loaded = append(loaded, gitLayer)
+
+	if path, err := GlobalPath(); err == nil {
+		values, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, layer{source: SourceGlobal, path: path, values: values})
+	}
+
+	if repoRoot != "" {
+		path := filepath.Join(repoRoot, RepoFileName)
+		values, err := readFile(path)
+		if err != nil {
+			return err
+		}
+		loaded = append(loaded, layer{source: SourceRepo, path: path, values: values})
+	}
+
+	envLayer := layer{source: SourceEnv, values: map[string]string{}}
+	for _, key := range Keys {
+		name := EnvName(key.Name)
+		if value, ok := os.LookupEnv(name); ok {
+			if err := validate(key.Name, value); err != nil {
+				return &Error{Path: name, Key: key.Name, Err: err}
+			}
+			envLayer.values[key.Name] = value
+		}
+	}
+	loaded = append(loaded, envLayer)
+
+	mu.Lock()
+	defer mu.Unlock()
+	layers = loaded
+	return nil
+}
+
+// Validate checks a value against the schema without storing it
+func Validate(name, value string) error {
+	if err := validate(name, value); err != nil {
+		return &Error{Key: name, Err: err}
+	}
+	return nil
+}
+
+// SetFlag sets a value from the command line, above every other layer
+func SetFlag(name, value string) error {
+	if err := Validate(name, value); err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	flags[name] = value
+	return nil
+}
+
+// Get returns the effective value of a setting
+func Get(name string) (Value, error) {
+	key, ok := Lookup(name)
+	if !ok {
+		return Value{}, &Error{Key: name, Err: ErrUnknownKey}
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if value, ok := flags[name]; ok {
+		return Value{Key: key, Value: value, Source: SourceFlag}, nil
+	}
+	for i := len(layers) - 1; i >= 0; i-- {
+		if value, ok := layers[i].values[name]; ok {
+			return Value{Key: key, Value: value, Source: layers[i].source, Path: layers[i].path}, nil
+		}
+	}
+	return Value{Key: key, Value: key.Default, Source: SourceDefault}, nil
+}
+
+// List returns the effective value of every setting, in schema order
+func List() []Value {
+	var values []Value
+	for _, key := range Keys {
+		value, _ := Get(key.Name)
+		values = append(values, value)
+	}
+	return values
+}
+
+// String returns a setting's value. Asking for a setting that is not in the
+// schema is a programming error.
+func String(name string) string {
+	value, err := Get(name)
+	if err != nil {
+		panic(err)
+	}
+	return value.Value
+}
+
+// Int returns an integer setting
+func Int(name string) int {
+	n, _ := strconv.Atoi(String(name))
+	return n
+}
+
+// Strings returns the items of a list setting; nil when unset
+func Strings(name string) []string {
+	value := String(name)
+	if value == "" {
+		return nil
+	}
+	return splitList(value)
+}
+
+// Size returns a size setting in bytes; zero when unset
+func Size(name string) int64 {
+	value := String(name)
+	if value == "" {
+		return 0
+	}
+	n, _ := ParseSize(value)
+	return n
+}
+
+// Duration returns a duration or age setting; zero when unset
+func Duration(name string) time.Duration {
+	value := String(name)
+	if value == "" {
+		return 0
+	}
+	d, _ := ParseAge(value)
+	return d
+}
+
+// Set stores a value in the repository's settings file, or the global one,
+// and returns the path written
+func Set(name, value string, global bool, repoRoot string) (string, error) {
+	if err := Validate(name, value); err != nil {
+		return "", err
+	}
+	key, _ := Lookup(name)
+
+	path, err := FilePath(global, repoRoot)
+	if err != nil {
+		return "", err
+	}
+
+	doc := map[string]interface{}{}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return "", fmt.Errorf("%s: %v", path, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	// Walk down to the object holding the last part of the name
+	parts := strings.Split(name, ".")
+	obj := doc
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := obj[part].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[part] = child
+		}
+		obj = child
+	}
+	obj[parts[len(parts)-1]] = key.Kind.jsonValue(value)
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// FilePath returns the settings file Set writes to
+func FilePath(global bool, repoRoot string) (string, error) {
+	if global {
+		return GlobalPath()
+	}
+	if repoRoot == "" {
+		return "", fmt.Errorf("not in a Git repository. Use --global to change the global settings")
+	}
+	return filepath.Join(repoRoot, RepoFileName), nil
+}
+
+// GlobalPath returns the user's settings file, under $XDG_CONFIG_HOME or ~/.config
+func GlobalPath() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "vibe-check", "config.json"), nil
+}
+
+// EnvName returns the environment variable that sets a setting
+func EnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(name, ".", "_"))
+}
+
+// readFile reads a settings file into setting names and values. A missing
+// file is not an error.
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	doc := map[string]interface{}{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	values := map[string]string{}
+	var walk func(prefix string, obj map[string]interface{}) error
+	walk = func(prefix string, obj map[string]interface{}) error {
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			full := prefix + name
+			if child, ok := obj[name].(map[string]interface{}); ok {
+				if err := walk(full+".", child); err != nil {
+					return err
+				}
+				continue
+			}
+			key, ok := Lookup(full)
+			if !ok {
+				return &Error{Path: path, Key: full, Err: ErrUnknownKey}
+			}
+			value, err := key.Kind.fromJSON(obj[name])
+			if err != nil {
+				return &Error{Path: path, Key: full, Err: err}
+			}
+			if err := validate(full, value); err != nil {
+				return &Error{Path: path, Key: full, Err: err}
+			}
+			values[full] = value
+		}
+		return nil
+	}
+	if err := walk("", doc); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
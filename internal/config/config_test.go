@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSettings(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLegacyGitConfigBelowGlobal(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Cleanup(func() { Load("", nil) })
+	writeSettings(t, filepath.Join(home, "vibe-check", "config.json"), `{"push": {"remote": "global"}}`)
+	repo := t.TempDir()
+	writeSettings(t, filepath.Join(repo, RepoFileName), `{"push": {"branch": "repo"}}`)
+
+	legacy := map[string]string{"push.remote": "legacy", "push.branch": "legacy", "push.refspec": "refs/heads/legacy"}
+	if err := Load(repo, legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := String("push.remote"); got != "global" {
+		t.Errorf("push.remote = %q, want the global file over git config", got)
+	}
+	if got := String("push.branch"); got != "repo" {
+		t.Errorf("push.branch = %q, want .vibecheck.json over git config", got)
+	}
+	if got, _ := Get("push.refspec"); got.Value != "refs/heads/legacy" || got.Source != SourceGitConfig {
+		t.Errorf("push.refspec = %+v, want the git config value over the default", got)
+	}
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the type of a setting's value
+type Kind int
+
+const (
+	KindString   Kind = iota
+	KindInt           // positive integer
+	KindDuration      // Go duration, e.g. 2s
+	KindAge           // duration that also accepts days and weeks, e.g. 7d
+	KindColor         // ANSI color number or #rrggbb
+	KindTimezone      // IANA timezone name, e.g. Europe/London
+	KindChoice        // one of the values listed in choices
+	KindList          // comma-separated values; a JSON array in settings files
+	KindSize          // size in bytes, or with a unit, e.g. 500KB or 5MB
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindInt:
+		return "integer"
+	case KindDuration:
+		return "duration"
+	case KindAge:
+		return "age"
+	case KindColor:
+		return "color"
+	case KindTimezone:
+		return "timezone"
+	case KindChoice:
+		return "choice"
+	case KindList:
+		return "list"
+	case KindSize:
+		return "size"
+	default:
+		return "string"
+	}
+}
+
+// Key describes one setting
+type Key struct {
+	Name        string
+	Kind        Kind
+	Default     string
+	Description string
+}
+
+// Keys is the schema: every setting vibe-check reads
+var Keys = []Key{
+	{"ui.note_limit", KindInt, "50", "Maximum length of a checkpoint note typed in the TUI"},
+	{"ui.message_limit", KindInt, "100", "Maximum length of a finalize message typed in the TUI"},
+	{"ui.refresh_interval", KindDuration, "2s", "How often the TUI menu re-reads the repository"},
+	{"ui.colors.accent", KindColor, "45", "Accent color: pointers, hunk headers"},
+	{"ui.colors.text", KindColor, "252", "Primary text color"},
+	{"ui.colors.muted", KindColor, "244", "Color of unselected menu items"},
+	{"ui.colors.info", KindColor, "110", "Color of titles and headings"},
+	{"ui.colors.success", KindColor, "114", "Color of the current checkpoint, successes and added lines"},
+	{"ui.colors.error", KindColor, "203", "Color of errors and removed lines"},
+
+	{"push.remote", KindString, "", "Remote finalize pushes to (default: the branch's push remote)"},
+	{"push.branch", KindString, "", "Branch finalize commits to and pushes (default: the branch's upstream)"},
+	{"push.refspec", KindString, "", "Refspec passed to git push (default: <branch>[:<upstream branch>])"},
+	{"push.fallback_remote", KindString, "origin", "Remote used when git config names none and there are several"},
+
+	{"checkpoint.scope", KindChoice, "repo", "What a checkpoint covers: repo (every change) or cwd (changes under the current directory)"},
+
+	{"scan.mode", KindChoice, "warn", "What the scan before checkpoints and pushes does with findings: block, warn or off"},
+	{"scan.max_file_size", KindSize, "5MB", "Files larger than this are reported by the scan"},
+	{"scan.allowlist", KindString, ".vibecheck-allow", "File at the repository root listing paths and rules the scan ignores"},
+
+	{"finalize.retention", KindAge, "", "How long finalized checkpoints are kept before gc may remove them"},
+
+	{"backups.prune_age", KindAge, "7d", "Backups older than this are deleted by `backups prune` and by p on the backups screen"},
+
+	{"workspace.repos", KindList, "", "Repositories `vibe-check workspace` works on together, relative to the file that lists them"},
+
+	{"watch.quiet_period", KindDuration, "10s", "How long the working tree must stay unchanged before watch checkpoints it"},
+	{"watch.min_interval", KindDuration, "1m", "Minimum time between two automatic checkpoints"},
+	{"watch.max_count", KindInt, "", "Stop watching after this many automatic checkpoints (default: no limit)"},
+	{"watch.poll_interval", KindDuration, "1s", "How often watch checks the working tree"},
+
+	{"messages.checkpoint_template", KindString, "CHECKPOINT: {{.Timestamp}}{{if .Note}} - {{.Note}}{{end}}", "Template of checkpoint subjects"},
+	{"messages.checkpoint_body_template", KindString, "", "Template of checkpoint bodies, written above the trailers"},
+	{"messages.finalize_template", KindString, "Update: {{.Timestamp}}", "Template of finalize commit messages"},
+	{"messages.time_format", KindString, "02/01/2006 15:04", "Go time layout of {{.Timestamp}}"},
+	{"messages.timezone", KindTimezone, "", "Timezone of {{.Timestamp}} (default: local time)"},
+}
+
+// choices lists the values each KindChoice setting accepts
+var choices = map[string][]string{
+	"checkpoint.scope": {"repo", "cwd"},
+	"scan.mode":        {"block", "warn", "off"},
+}
+
+// Lookup finds a setting in the schema
+func Lookup(name string) (Key, bool) {
+	for _, key := range Keys {
+		if key.Name == name {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+var colorPattern = regexp.MustCompile(`^(#[0-9a-fA-F]{6}|#[0-9a-fA-F]{3}|[0-9]{1,3})$`)
+
+// validate checks value against the kind of setting name
+func validate(name, value string) error {
+	key, ok := Lookup(name)
+	if !ok {
+		return ErrUnknownKey
+	}
+	// An empty value leaves optional settings unset
+	if value == "" && key.Default == "" {
+		return nil
+	}
+
+	switch key.Kind {
+	case KindInt:
+		if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+			return fmt.Errorf("must be a positive integer, got %q", value)
+		}
+	case KindDuration:
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("must be a positive duration such as 2s or 500ms, got %q", value)
+		}
+	case KindAge:
+		if _, err := ParseAge(value); err != nil {
+			return err
+		}
+	case KindColor:
+		if !colorPattern.MatchString(value) {
+			return fmt.Errorf("must be an ANSI color number or #rrggbb, got %q", value)
+		}
+		if n, err := strconv.Atoi(value); err == nil && n > 255 {
+			return fmt.Errorf("ANSI colors go up to 255, got %q", value)
+		}
+	case KindTimezone:
+		if _, err := time.LoadLocation(value); err != nil {
+			return fmt.Errorf("unknown timezone %q", value)
+		}
+	case KindChoice:
+		valid := choices[name]
+		if !slices.Contains(valid, value) {
+			return fmt.Errorf("must be one of %s, got %q", strings.Join(valid, ", "), value)
+		}
+	case KindSize:
+		if _, err := ParseSize(value); err != nil {
+			return err
+		}
+	case KindList:
+		if len(splitList(value)) == 0 {
+			return fmt.Errorf("must list at least one item")
+		}
+	default:
+		if value == "" {
+			return fmt.Errorf("must not be empty")
+		}
+	}
+	return nil
+}
+
+// fromJSON converts a value decoded from a settings file to its string form
+func (k Kind) fromJSON(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		if k == KindList {
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok || s == "" || strings.Contains(s, ",") {
+					return "", fmt.Errorf("must be an array of non-empty strings without commas")
+				}
+				items = append(items, s)
+			}
+			return strings.Join(items, ","), nil
+		}
+	case float64:
+		if k == KindInt || k == KindColor {
+			if v != float64(int(v)) {
+				return "", fmt.Errorf("must be a whole number")
+			}
+			return strconv.Itoa(int(v)), nil
+		}
+	}
+	return "", fmt.Errorf("must be a %s given as a JSON %s", k, k.jsonType())
+}
+
+// jsonType names the JSON type settings of this kind are written as
+func (k Kind) jsonType() string {
+	switch k {
+	case KindInt:
+		return "number"
+	case KindList:
+		return "array"
+	}
+	return "string"
+}
+
+// jsonValue converts a validated value to what is written to a settings file
+func (k Kind) jsonValue(value string) interface{} {
+	switch k {
+	case KindInt:
+		n, _ := strconv.Atoi(value)
+		return n
+	case KindList:
+		return splitList(value)
+	}
+	return value
+}
+
+// splitList splits a list setting into its non-empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// ParseAge parses an age such as "36h", "7d" or "2w"
+func ParseAge(value string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(value, suffix); ok {
+			count, err := strconv.Atoi(n)
+			if err != nil || count < 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(count) * unit, nil
+		}
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 12h, 7d or 2w)", value)
+	}
+	return age, nil
+}
+
+// sizeUnits are the units ParseSize accepts, largest first
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"B", 1}}
+
+// ParseSize parses a size such as "500KB", "5MB" or "1048576" into bytes
+func ParseSize(value string) (int64, error) {
+	upper := strings.ToUpper(strings.TrimSpace(value))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if n, ok := strings.CutSuffix(upper, u.suffix); ok {
+			upper, unit = strings.TrimSpace(n), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q (use e.g. 500KB or 5MB)", value)
+	}
+	return n * unit, nil
+}
@@ -0,0 +1,208 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"vibe-check/internal/models"
+)
+
+// ErrBackupBehind is returned when restoring a backup would drop commits made
+// on its branch after the backup was taken
+var ErrBackupBehind = errors.New("the branch has commits the backup does not")
+
+// ListBackups returns the backup branches finalize left behind, newest first
+func (r *Repo) ListBackups() ([]models.Backup, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+
+	output, err := r.RunCommand("for-each-ref",
+		"--format=%(refname:short)"+fieldSep+"%(objectname:short)"+fieldSep+"%(committerdate:unix)"+recordSep,
+		"refs/heads/"+backupBranchPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	backups := []models.Backup{}
+	for _, fields := range splitRecords(output) {
+		if len(fields) < 3 {
+			continue
+		}
+		backup := models.Backup{Name: fields[0], Hash: fields[1]}
+
+		// The name carries the creation time; fall back to the commit date
+		seconds, err := strconv.ParseInt(strings.TrimPrefix(backup.Name, backupBranchPrefix), 10, 64)
+		if err != nil {
+			seconds, _ = strconv.ParseInt(fields[2], 10, 64)
+		}
+		backup.Time = time.Unix(seconds, 0)
+		backup.Branch = r.gitConfig("branch." + backup.Name + "." + backupOriginKey)
+
+		backup.DiffStat = "no HEAD to compare with"
+		if stat, err := r.RunCommand("diff", "--shortstat", "HEAD", backup.Name); err == nil {
+			backup.DiffStat = stat
+			if stat == "" {
+				backup.DiffStat = "same as HEAD"
+			}
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.SliceStable(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// FindBackup looks up a backup by branch name or by its timestamp suffix
+func (r *Repo) FindBackup(name string) (*models.Backup, error) {
+	backups, err := r.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	for _, backup := range backups {
+		if backup.Name == name || backup.Name == backupBranchPrefix+name {
+			found := backup
+			return &found, nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found. Run `vibe-check backups` to list them", name)
+}
+
+// RestoreBackup points the backup's original branch at the backup, checks
+// it out and brings back the checkpoints it contains. Uncommitted changes are
+// carried over when git allows it. The backup branch itself is kept. Unless
+// force is set, it refuses when the branch has moved on since the backup.
+func (r *Repo) RestoreBackup(name string, force bool) (*models.Backup, error) {
+	backup, err := r.FindBackup(name)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := backup.Branch
+	if branch == "" || branch == detachedNamespace {
+		current, err := r.GetCurrentBranch()
+		if err != nil || current == "HEAD" {
+			return nil, fmt.Errorf("backup %s does not record its branch. Checkout the branch to restore it onto first", backup.Name)
+		}
+		branch = current
+	}
+	if !force {
+		if err := r.checkBackupDrops(backup.Name, branch); err != nil {
+			return nil, err
+		}
+	}
+
+	op := r.beginOperation(OpRestore)
+	if output, err := r.RunCommand("checkout", "-B", branch, backup.Name); err != nil {
+		return nil, fmt.Errorf("failed to restore %s onto %s: %v\n%s", backup.Name, branch, err, output)
+	}
+	if err := r.clearOriginBranch(); err != nil {
+		return nil, fmt.Errorf("restored %s but failed to update state: %v", backup.Name, err)
+	}
+	if err := r.restoreCheckpointRefs(branch, backup.Name); err != nil {
+		return nil, fmt.Errorf("restored %s but failed to recover its checkpoints: %v", backup.Name, err)
+	}
+	op.finish(fmt.Sprintf("restore %s onto %s", backup.Name, branch))
+
+	backup.Branch = branch
+	return backup, nil
+}
+
+// checkBackupDrops fails with ErrBackupBehind, naming the commits, when
+// resetting branch to backup would drop commits from it
+func (r *Repo) checkBackupDrops(backup, branch string) error {
+	ref := "refs/heads/" + branch
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", ref); err != nil {
+		return nil
+	}
+	if _, err := r.RunCommand("merge-base", "--is-ancestor", ref, backup); err == nil {
+		return nil
+	}
+	output, err := r.RunCommand("log", "--format=%h %s", backup+".."+ref)
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("%w. Restoring %s onto %s would drop:\n  %s",
+		ErrBackupBehind, backup, branch, strings.ReplaceAll(output, "\n", "\n  "))
+}
+
+// restoreCheckpointRefs re-records the checkpoints at the tip of a commit's
+// history, up to the first regular commit
+func (r *Repo) restoreCheckpointRefs(branch, tip string) error {
+	output, err := r.RunCommand("log", "--format=%H"+fieldSep+"%ct"+fieldSep+"%B"+recordSep, tip)
+	if err != nil {
+		return err
+	}
+	for i, fields := range splitRecords(output) {
+		if len(fields) < 3 {
+			continue
+		}
+		cp, ok := ParseCheckpoint(fields[0], fields[2])
+		if !ok {
+			break
+		}
+		// Legacy checkpoints carry no id; derive a sortable one like the reflog migration does
+		id := cp.ID
+		if id == "" {
+			seconds, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			id = strconv.FormatInt(seconds*int64(time.Second)-int64(i), 10)
+		}
+		if err := r.recordCheckpointRef(branch, id, fields[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DiffBackup returns the difference between HEAD and a backup, as a patch or a diffstat
+func (r *Repo) DiffBackup(name string, stat bool) (string, error) {
+	backup, err := r.FindBackup(name)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{"diff"}
+	if stat {
+		args = append(args, "--stat")
+	}
+	args = append(args, "HEAD", backup.Name)
+
+	// Keep the output's leading alignment, which RunCommand would trim
+	result, err := r.Exec(r.commandContext(), Invocation{Args: args})
+	if err != nil {
+		return "", fmt.Errorf("failed to diff against %s: %v", backup.Name, err)
+	}
+	return strings.TrimRight(result.Stdout, "\n"), nil
+}
+
+// PruneBackups deletes backups older than maxAge and returns the ones removed
+func (r *Repo) PruneBackups(maxAge time.Duration) ([]models.Backup, error) {
+	backups, err := r.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var pruned []models.Backup
+	op := r.beginOperation(OpPrune)
+	for _, backup := range backups {
+		if !backup.Time.Before(cutoff) {
+			continue
+		}
+		// -D also drops the branch's config, including the recorded origin
+		if _, err := r.RunCommand("branch", "-D", backup.Name); err != nil {
+			return pruned, fmt.Errorf("failed to delete backup %s: %v", backup.Name, err)
+		}
+		pruned = append(pruned, backup)
+	}
+	op.finish(fmt.Sprintf("prune %d backup(s)", len(pruned)))
+	return pruned, nil
+}
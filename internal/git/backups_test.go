@@ -0,0 +1,63 @@
+package git
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// leaveBackup makes a finalize fail so it keeps a backup of the branch, and returns its name
+func (r *testRepo) leaveBackup() string {
+	r.t.Helper()
+	r.checkpoint("one\n")
+	r.rejectPushes()
+	if _, err := r.FinalizeAndPushWithMessage("Ship it"); err == nil {
+		r.t.Fatal("finalize succeeded although pushes are rejected")
+	}
+	backups := r.backups()
+	if len(backups) != 1 {
+		r.t.Fatalf("backup branches = %v, want one", backups)
+	}
+	return backups[0]
+}
+
+func TestRestoreBackupRefusesToDropNewerCommits(t *testing.T) {
+	r := newTestRepo(t)
+	backup := r.leaveBackup()
+	r.write("later.txt", "later\n")
+	r.git(r.dir, "add", "-A")
+	r.git(r.dir, "commit", "-q", "-m", "later work")
+	head := r.git(r.dir, "rev-parse", "HEAD")
+
+	_, err := r.RestoreBackup(backup, false)
+
+	if !errors.Is(err, ErrBackupBehind) {
+		t.Fatalf("expected ErrBackupBehind, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "later work") {
+		t.Errorf("error does not name the commit that would be dropped:\n%v", err)
+	}
+	if got := r.git(r.dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD moved to %s", got)
+	}
+
+	if _, err := r.RestoreBackup(backup, true); err != nil {
+		t.Fatalf("forced restore failed: %v", err)
+	}
+	if got, want := r.git(r.dir, "rev-parse", "main"), r.git(r.dir, "rev-parse", backup); got != want {
+		t.Errorf("main at %s, want the backup %s", got, want)
+	}
+}
+
+func TestRestoreBackupWhenBranchIsBehind(t *testing.T) {
+	r := newTestRepo(t)
+	backup := r.leaveBackup()
+	r.git(r.dir, "reset", "-q", "--hard", "HEAD^")
+
+	if _, err := r.RestoreBackup(backup, false); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if got, want := r.git(r.dir, "rev-parse", "main"), r.git(r.dir, "rev-parse", backup); got != want {
+		t.Errorf("main at %s, want the backup %s", got, want)
+	}
+}
@@ -1,130 +1,294 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 	"vibe-check/internal/models"
+	"vibe-check/internal/scan"
 )
 
+// ErrNoChanges is returned when there is nothing to checkpoint
+var ErrNoChanges = errors.New("no changes to checkpoint")
+
+// ErrCheckpointNotFound is returned when a hash or id matches no checkpoint
+var ErrCheckpointNotFound = errors.New("checkpoint not found")
+
+// CheckpointOptions controls how a checkpoint is created
+type CheckpointOptions struct {
+	Note string
+	// Shadow snapshots the working tree and index into a side ref
+	// instead of committing on the current branch
+	Shadow bool
+	// Paths limits the checkpoint to these pathspecs, relative to the
+	// directory vibe-check was started in; empty means every change in scope
+	Paths []string
+	// Interactive asks which hunks to include, like `git add --patch`
+	Interactive bool
+}
+
+// partial reports whether only part of the changes go into the checkpoint
+func (o CheckpointOptions) partial() bool {
+	return len(o.Paths) > 0 || o.Interactive
+}
+
 // CreateCheckpoint creates a new git checkpoint
-func CreateCheckpoint(customNote string) error {
-	if !IsRepo() {
-		return fmt.Errorf("not in a Git repository")
+func (r *Repo) CreateCheckpoint(customNote string) error {
+	_, err := r.CreateCheckpointWithOptions(CheckpointOptions{Note: customNote})
+	return err
+}
+
+// CreateCheckpointWithOptions creates a new git checkpoint using the given options
+// and returns it
+func (r *Repo) CreateCheckpointWithOptions(opts CheckpointOptions) (*models.Checkpoint, error) {
+	customNote := opts.Note
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+	if opts.Shadow && (opts.partial() || r.GetScope() == ScopeCwd) {
+		return nil, fmt.Errorf("shadow checkpoints capture the whole working tree and cannot be limited to paths, hunks or --scope=cwd")
 	}
 
-	// Check if there are changes to commit
-	status, err := RunCommand("status", "--porcelain")
+	// Check if there are changes to commit within the scope
+	status, err := r.RunCommand("status", "--porcelain", "--", r.scopePathspec())
 	if err != nil {
-		return fmt.Errorf("failed to check git status: %v", err)
+		return nil, fmt.Errorf("failed to check git status: %v", err)
 	}
 
 	if status == "" {
-		return fmt.Errorf("no changes to checkpoint")
+		return nil, ErrNoChanges
 	}
 
-	// Add all changes
-	_, err = RunCommand("add", ".")
+	// Look for secrets and large files before anything is recorded
+	pathspecs := []string{r.scopePathspec()}
+	if len(opts.Paths) > 0 {
+		pathspecs = nil
+		for _, p := range opts.Paths {
+			pathspecs = append(pathspecs, r.userPathspec(p))
+		}
+	}
+	findings, err := r.runScan("checkpoint", func() ([]scan.File, error) {
+		return r.worktreeFiles(pathspecs)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to add changes: %v", err)
+		return nil, err
 	}
 
-	// Create commit message
-	timestamp := GetTimestamp()
-	var message string
-	if customNote != "" {
-		message = fmt.Sprintf("CHECKPOINT: %s - %s", timestamp, customNote)
-	} else {
-		message = fmt.Sprintf("CHECKPOINT: %s", timestamp)
+	// Resolve the checkpoint namespace before HEAD moves
+	branch, err := r.GetCheckpointBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine current branch: %v", err)
+	}
+
+	id := newCheckpointID()
+
+	// Only the selected files and hunks go into a partial checkpoint
+	if opts.partial() {
+		op := r.beginOperation(OpCreate)
+		commit, message, err := r.createPartialCheckpoint(opts, branch, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.recordCheckpointRef(branch, id, commit); err != nil {
+			return nil, err
+		}
+		op.finish("create " + message)
+		return newCheckpoint(commit, message, id, branch, customNote, false, findings), nil
 	}
 
-	// Create commit
-	_, err = RunCommand("commit", "-m", message)
+	// Create commit message
+	data, err := r.checkpointMessageData(branch, customNote)
+	if err != nil {
+		return nil, err
+	}
+	message, body, err := renderCheckpointMessage(data)
 	if err != nil {
-		return fmt.Errorf("failed to create checkpoint: %v", err)
+		return nil, err
 	}
+	op := r.beginOperation(OpCreate)
 
-	return nil
-}
+	// Shadow checkpoints leave HEAD, the branch and the index alone
+	if opts.Shadow {
+		commit, err := r.createShadowCheckpoint(branch, id, message, body, customNote)
+		if err != nil {
+			return nil, err
+		}
+		op.finish("create shadow " + message)
+		return newCheckpoint(commit, message, id, branch, customNote, true, findings), nil
+	}
 
-// GetCheckpointsFromHistory returns checkpoints from commit history
-func GetCheckpointsFromHistory() ([]models.Checkpoint, error) {
-	output, err := RunCommand("log", "--oneline", "--grep=CHECKPOINT", "--format=%h %s")
+	// Add all changes in scope, wherever in the repository vibe-check runs
+	_, err = r.RunCommand("add", "-A", "--", r.scopePathspec())
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to add changes: %v", err)
 	}
 
-	if strings.TrimSpace(output) == "" {
-		return []models.Checkpoint{}, nil
+	// Create commit, marked as a checkpoint with trailers
+	args := append([]string{"commit"}, checkpointMessageArgs(id, message, body, customNote)...)
+	if r.GetScope() == ScopeCwd {
+		// Leave changes staged outside the current directory out of the commit
+		args = append(args, "--", r.scopePathspec())
+	}
+	_, err = r.RunCommand(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint: %v", err)
 	}
 
-	lines := strings.Split(output, "\n")
-	var checkpoints []models.Checkpoint
+	// Record the checkpoint under its own ref so it survives reflog expiry
+	commit, err := r.RunCommand("rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve checkpoint commit: %v", err)
+	}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+	if err := r.recordCheckpointRef(branch, id, commit); err != nil {
+		return nil, err
+	}
+	op.finish("create " + message)
+	return newCheckpoint(commit, message, id, branch, customNote, false, findings), nil
+}
+
+// checkpointMessageData returns template data describing every uncommitted change
+func (r *Repo) checkpointMessageData(branch, note string) (MessageData, error) {
+	data, err := newMessageData(branch, note)
+	if err != nil {
+		return MessageData{}, err
+	}
+	data.files = r.ChangedFiles
+	data.diffStat = r.worktreeDiffStat
+	data.count = func() (int, error) {
+		checkpoints, err := r.GetCheckpoints()
+		if err != nil {
+			return 0, err
+		}
+		count := 1 // the one being created
+		for _, cp := range checkpoints {
+			if cp.ID != "" {
+				count++
+			}
 		}
+		return count, nil
+	}
+	return data, nil
+}
 
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 || !strings.Contains(parts[1], "CHECKPOINT:") {
-			continue
+// renderCheckpointMessage renders the subject and body of a new checkpoint from the configured templates
+func renderCheckpointMessage(data MessageData) (subject, body string, err error) {
+	if subject, err = renderMessage(configCheckpointTemplate, data); err != nil {
+		return "", "", err
+	}
+	if subject == "" {
+		return "", "", fmt.Errorf("%s rendered an empty subject", configCheckpointTemplate)
+	}
+	if body, err = renderMessage(configCheckpointBodyTemplate, data); err != nil {
+		return "", "", err
+	}
+	return subject, body, nil
+}
+
+// ChangedFiles lists every uncommitted file in scope once, untracked ones included
+func (r *Repo) ChangedFiles() ([]string, error) {
+	groups, err := r.uncommittedFiles()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var files []string
+	for _, group := range groups {
+		for _, file := range group.Files {
+			if !seen[file] && r.scopedPath(file) {
+				seen[file] = true
+				files = append(files, file)
+			}
 		}
+	}
+	return files, nil
+}
 
-		checkpoints = append(checkpoints, models.Checkpoint{
-			Hash:    parts[0],
-			Message: parts[1],
-		})
+// worktreeDiffStat summarises the uncommitted changes, untracked files included
+func (r *Repo) worktreeDiffStat() (string, error) {
+	// The snapshot writes to a temporary index, which a dry run only plans
+	if r.IsDryRun() {
+		return "", nil
+	}
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return "", nil // nothing to compare an unborn branch with
+	}
+	tree, err := r.snapshotWorktreeTree()
+	if err != nil {
+		return "", err
 	}
+	return r.RunCommand("diff", "--shortstat", "HEAD", tree, "--", r.scopePathspec())
+}
 
-	return checkpoints, nil
+// newCheckpoint describes a checkpoint that was just recorded
+func newCheckpoint(commit, message, id, branch, note string, shadow bool, findings []models.Finding) *models.Checkpoint {
+	return &models.Checkpoint{
+		Hash:     shortHash(commit),
+		SHA:      commit,
+		Message:  message,
+		Time:     time.Now(),
+		ID:       id,
+		Ref:      CheckpointRef(branch, id),
+		Branch:   branch,
+		Note:     note,
+		Shadow:   shadow,
+		Findings: findings,
+	}
 }
 
-// GetCheckpointsFromReflog returns checkpoints from reflog (includes navigation history)
-func GetCheckpointsFromReflog() ([]models.Checkpoint, error) {
-	// Get all reflog entries to find all commits (including unreachable ones)
-	reflogOutput, err := RunCommand("reflog", "--format=%h %s")
+// GetCheckpointsFromHistory returns checkpoints from commit history
+func (r *Repo) GetCheckpointsFromHistory() ([]models.Checkpoint, error) {
+	output, err := r.RunCommand("log", "--grep=^"+TrailerCheckpoint+": ", "--grep=^"+legacyCheckpointPrefix,
+		"--format=%h"+fieldSep+"%B"+recordSep)
 	if err != nil {
 		return nil, err
 	}
 
-	var checkpoints []models.Checkpoint
-	seen := make(map[string]bool)
+	checkpoints := []models.Checkpoint{}
+	for _, fields := range splitRecords(output) {
+		if len(fields) < 2 {
+			continue
+		}
 
-	if strings.TrimSpace(reflogOutput) != "" {
-		lines := strings.Split(reflogOutput, "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
+		// --grep only narrows the search; the parser decides what is a checkpoint
+		if cp, ok := ParseCheckpoint(fields[0], fields[1]); ok {
+			checkpoints = append(checkpoints, cp)
+		}
+	}
 
-			parts := strings.SplitN(line, " ", 2)
-			if len(parts) < 2 {
-				continue
-			}
+	return checkpoints, nil
+}
 
-			hash := parts[0]
-			message := parts[1]
+// GetCheckpoints returns the checkpoints recorded for the current branch, newest first.
+// The last regular commit is appended at the end so it can be switched back to.
+func (r *Repo) GetCheckpoints() ([]models.Checkpoint, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
 
-			// Only include checkpoints and avoid duplicates
-			if strings.Contains(message, "CHECKPOINT:") && !seen[hash] {
-				seen[hash] = true
-				checkpoints = append(checkpoints, models.Checkpoint{
-					Hash:    hash,
-					Message: message,
-				})
-			}
-		}
+	// Bring over checkpoints created before the ref namespace existed
+	if err := r.migrateReflogCheckpoints(); err != nil {
+		return nil, fmt.Errorf("failed to migrate reflog checkpoints: %v", err)
+	}
+
+	branch, err := r.GetCheckpointBranch()
+	if err != nil {
+		return nil, err
 	}
 
-	// Reverse to show newest first (reflog is oldest to newest)
-	for i, j := 0, len(checkpoints)-1; i < j; i, j = i+1, j-1 {
-		checkpoints[i], checkpoints[j] = checkpoints[j], checkpoints[i]
+	checkpoints, err := r.listCheckpointRefs(branch)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, cp := range checkpoints {
+		seen[cp.Hash] = true
 	}
 
 	// Add the last non-checkpoint commit at the end if it exists
-	lastNonCheckpoint, err := GetLastNonCheckpointCommit()
+	lastNonCheckpoint, err := r.GetLastNonCheckpointCommit()
 	if err == nil && lastNonCheckpoint != nil {
 		// Check if this commit is not already in the list
 		if !seen[lastNonCheckpoint.Hash] {
@@ -137,50 +301,116 @@ func GetCheckpointsFromReflog() ([]models.Checkpoint, error) {
 	return checkpoints, nil
 }
 
+// GetCheckpointsFromReflog returns checkpoints for the current branch.
+//
+// Deprecated: checkpoints are no longer read from the reflog; use GetCheckpoints.
+func (r *Repo) GetCheckpointsFromReflog() ([]models.Checkpoint, error) {
+	return r.GetCheckpoints()
+}
+
+// FindCheckpoint looks up a checkpoint of the current branch by hash prefix or id
+func (r *Repo) FindCheckpoint(ref string) (*models.Checkpoint, error) {
+	checkpoints, err := r.GetCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cp := range checkpoints {
+		if cp.ID == ref || strings.HasPrefix(cp.Hash, ref) || strings.HasPrefix(ref, cp.Hash) {
+			found := cp
+			return &found, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrCheckpointNotFound, ref)
+}
+
 // SwitchToCheckpoint switches to a specific checkpoint
-func SwitchToCheckpoint(hash string) error {
-	if !IsRepo() {
-		return fmt.Errorf("not in a Git repository")
+func (r *Repo) SwitchToCheckpoint(hash string) error {
+	if !r.IsRepo() {
+		return ErrNotRepo
+	}
+
+	checkpoint, err := r.FindCheckpoint(hash)
+	if err != nil {
+		return err
 	}
 
 	// Check if we're already on this checkpoint
-	currentCommit, err := GetCurrentCommit()
-	if err == nil && currentCommit == hash {
+	currentCommit, err := r.GetCurrentCheckpointHash()
+	if err == nil && currentCommit == checkpoint.Hash {
 		return fmt.Errorf("you are already on checkpoint %s", hash)
 	}
 
-	_, err = RunCommand("checkout", hash)
+	op := r.beginOperation(OpSwitch)
+	summary := fmt.Sprintf("switch to [%s] %s", checkpoint.Hash, checkpoint.Message)
+
+	// Shadow checkpoints are restored into the working tree, not checked out
+	if checkpoint.Shadow {
+		if err := r.restoreShadowCheckpoint(*checkpoint); err != nil {
+			return fmt.Errorf("failed to switch to checkpoint %s: %v", hash, err)
+		}
+		op.finish(summary)
+		return nil
+	}
+
+	// The tip of the original branch is checked out as the branch itself
+	origin := r.GetOriginBranch()
+	if current, err := r.GetCurrentBranch(); err == nil && current != "HEAD" {
+		origin = current
+	}
+	if origin != "" && r.isBranchTip(origin, checkpoint.Hash) {
+		if _, err := r.RunCommand("checkout", origin); err != nil {
+			return fmt.Errorf("failed to switch to checkpoint %s: %v", hash, err)
+		}
+		err := r.clearOriginBranch()
+		op.finish(summary)
+		return err
+	}
+
+	// Remember where we came from so finalize and `vibe-check return` can get back
+	if err := r.recordOriginBranch(); err != nil {
+		return fmt.Errorf("failed to record current branch: %v", err)
+	}
+
+	_, err = r.RunCommand("checkout", checkpoint.Hash)
 	if err != nil {
 		return fmt.Errorf("failed to switch to checkpoint %s: %v", hash, err)
 	}
 
+	op.finish(summary)
 	return nil
 }
 
+// isBranchTip reports whether commit is the tip of a local branch
+func (r *Repo) isBranchTip(branch, commit string) bool {
+	tip, err := r.RunCommand("rev-parse", "refs/heads/"+branch)
+	if err != nil {
+		return false
+	}
+	full, err := r.RunCommand("rev-parse", commit+"^{commit}")
+	return err == nil && full == tip
+}
+
 // GetLastNonCheckpointCommit finds the last commit that is not a checkpoint
-func GetLastNonCheckpointCommit() (*models.Checkpoint, error) {
-	output, err := RunCommand("log", "--oneline", "--format=%h %s")
+func (r *Repo) GetLastNonCheckpointCommit() (*models.Checkpoint, error) {
+	output, err := r.RunCommand("log", "--format=%h"+fieldSep+"%H"+fieldSep+"%ct"+fieldSep+"%B"+recordSep)
 	if err != nil {
 		return nil, err
 	}
 
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 {
+	for _, fields := range splitRecords(output) {
+		if len(fields) < 4 {
 			continue
 		}
 
 		// If this commit is NOT a checkpoint, return it
-		if !strings.Contains(parts[1], "CHECKPOINT:") {
+		if cp, ok := ParseCheckpoint(fields[0], fields[3]); !ok {
 			return &models.Checkpoint{
-				Hash:    parts[0],
-				Message: parts[1],
+				Hash:    cp.Hash,
+				SHA:     fields[1],
+				Message: cp.Message,
+				Time:    parseUnix(fields[2]),
 			}, nil
 		}
 	}
@@ -189,8 +419,8 @@ func GetLastNonCheckpointCommit() (*models.Checkpoint, error) {
 }
 
 // HasCheckpoints returns true if any checkpoints exist
-func HasCheckpoints() bool {
-	checkpoints, err := GetCheckpointsFromReflog()
+func (r *Repo) HasCheckpoints() bool {
+	checkpoints, err := r.GetCheckpoints()
 	if err != nil {
 		return false
 	}
@@ -198,7 +428,7 @@ func HasCheckpoints() bool {
 	// Count only actual checkpoints (not the last non-checkpoint commit)
 	checkpointCount := 0
 	for _, cp := range checkpoints {
-		if strings.Contains(cp.Message, "CHECKPOINT:") {
+		if cp.ID != "" {
 			checkpointCount++
 		}
 	}
@@ -207,12 +437,12 @@ func HasCheckpoints() bool {
 }
 
 // IsCurrentCommitCheckpoint returns true if the current commit is a checkpoint
-func IsCurrentCommitCheckpoint() bool {
+func (r *Repo) IsCurrentCommitCheckpoint() bool {
 	// Get current commit message
-	output, err := RunCommand("log", "--oneline", "-1", "--format=%s")
+	output, err := r.RunCommand("log", "-1", "--format=%B")
 	if err != nil {
 		return false
 	}
 	
-	return strings.Contains(output, "CHECKPOINT:")
+	return IsCheckpointMessage(output)
 }
\ No newline at end of file
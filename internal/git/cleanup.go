@@ -0,0 +1,185 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"vibe-check/internal/config"
+	"vibe-check/internal/models"
+)
+
+// FinalizedRefPrefix is where finalized checkpoints are kept during the retention window.
+// Each one lives at refs/vibe-check-finalized/<unix>/<branch>/<id>, <unix> being when it expires.
+const FinalizedRefPrefix = "refs/vibe-check-finalized/"
+
+// Retention returns how long finalized checkpoints are kept, from the
+// finalize.retention setting. Zero means they are deleted as soon as finalize succeeds.
+func Retention() time.Duration {
+	return config.Duration("finalize.retention")
+}
+
+// retireCheckpoints removes the refs of finalized checkpoints. With a retention
+// window they are moved aside instead, and expired ones are pruned. Nothing
+// outside vibe-check's own refs is touched.
+func (r *Repo) retireCheckpoints(checkpoints []models.Checkpoint) error {
+	retention := Retention()
+	if retention == 0 {
+		return r.deleteCheckpointRefs(checkpoints)
+	}
+
+	expires := strconv.FormatInt(time.Now().Add(retention).Unix(), 10)
+	for _, cp := range checkpoints {
+		if cp.Ref == "" {
+			continue
+		}
+		kept := FinalizedRefPrefix + expires + "/" + cp.Branch + "/" + cp.ID
+		if _, err := r.RunCommand("update-ref", kept, cp.Ref); err != nil {
+			return fmt.Errorf("failed to keep finalized checkpoint %s: %v", cp.Hash, err)
+		}
+	}
+	if err := r.deleteCheckpointRefs(checkpoints); err != nil {
+		return err
+	}
+
+	expired, err := r.expiredFinalizedRefs()
+	if err != nil {
+		return err
+	}
+	return r.deleteRefs(expired)
+}
+
+// expiredFinalizedRefs returns the kept finalized checkpoints whose retention has passed
+func (r *Repo) expiredFinalizedRefs() ([]string, error) {
+	output, err := r.RunCommand("for-each-ref", "--format=%(refname)", FinalizedRefPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list finalized checkpoints: %v", err)
+	}
+
+	now := time.Now()
+	var expired []string
+	for _, ref := range strings.Split(output, "\n") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		stamp, _, _ := strings.Cut(strings.TrimPrefix(ref, FinalizedRefPrefix), "/")
+		seconds, err := strconv.ParseInt(stamp, 10, 64)
+		if err != nil || time.Unix(seconds, 0).Before(now) {
+			expired = append(expired, ref)
+		}
+	}
+	return expired, nil
+}
+
+// deleteRefs deletes the given refs
+func (r *Repo) deleteRefs(refs []string) error {
+	for _, ref := range refs {
+		if _, err := r.RunCommand("update-ref", "-d", ref); err != nil {
+			return fmt.Errorf("failed to delete %s: %v", ref, err)
+		}
+	}
+	return nil
+}
+
+// PlanGC previews what `vibe-check gc` would remove from the repository
+func (r *Repo) PlanGC() (*models.GCPlan, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+
+	plan := &models.GCPlan{}
+
+	var err error
+	if plan.ExpiredCheckpoints, err = r.expiredFinalizedRefs(); err != nil {
+		return nil, err
+	}
+
+	output, err := r.RunCommand("log", "-g", "--all", "--format=%h")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reflogs: %v", err)
+	}
+	if output != "" {
+		plan.ReflogEntries = len(strings.Split(output, "\n"))
+	}
+
+	// Ignoring reflogs shows what the expiry would leave unreachable
+	output, err = r.RunCommand("fsck", "--unreachable", "--no-reflogs", "--no-progress")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unreachable objects: %v", err)
+	}
+	// Passed on stdin: there can be more commits than fit on a command line
+	var commits strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || fields[0] != "unreachable" {
+			continue
+		}
+		plan.UnreachableObjects++
+		if fields[1] == "commit" {
+			commits.WriteString(fields[2] + "\n")
+		}
+	}
+
+	if commits.Len() > 0 {
+		inv := Invocation{
+			Args:  []string{"log", "--no-walk", "--stdin", "--format=%h" + fieldSep + "%ct" + fieldSep + "%s" + recordSep},
+			Stdin: strings.NewReader(commits.String()),
+		}
+		result, err := r.Exec(r.commandContext(), inv)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe unreachable commits: %v", err)
+		}
+		for _, fields := range splitRecords(result.Stdout) {
+			if len(fields) < 3 {
+				continue
+			}
+			plan.UnreachableCommits = append(plan.UnreachableCommits, models.Checkpoint{
+				Hash:    fields[0],
+				Time:    parseUnix(fields[1]),
+				Message: fields[2],
+			})
+		}
+	}
+
+	return plan, nil
+}
+
+// RunGC expires every reflog and prunes unreachable objects, along with
+// finalized checkpoints past their retention. This cannot be undone.
+func (r *Repo) RunGC() (*models.GCPlan, error) {
+	plan, err := r.PlanGC()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.deleteRefs(plan.ExpiredCheckpoints); err != nil {
+		return nil, err
+	}
+	if _, err := r.RunCommand("reflog", "expire", "--expire=now", "--all"); err != nil {
+		return nil, fmt.Errorf("failed to expire reflogs: %v", err)
+	}
+	if _, err := r.RunCommand("gc", "--prune=now"); err != nil {
+		return nil, fmt.Errorf("failed to prune objects: %v", err)
+	}
+	return plan, nil
+}
+
+// FormatGCPlan renders a gc preview for display
+func FormatGCPlan(plan *models.GCPlan) string {
+	var s strings.Builder
+
+	s.WriteString(fmt.Sprintf("Reflog entries to expire: %d (all refs)\n", plan.ReflogEntries))
+	s.WriteString(fmt.Sprintf("Finalized checkpoints past retention: %d\n", len(plan.ExpiredCheckpoints)))
+	s.WriteString(fmt.Sprintf("Unreachable objects to prune: %d\n", plan.UnreachableObjects))
+
+	if len(plan.UnreachableCommits) > 0 {
+		s.WriteString("\nCommits that will no longer be recoverable:\n")
+		for _, c := range plan.UnreachableCommits {
+			s.WriteString(fmt.Sprintf("  [%s] %s  %s\n", c.Hash, c.Time.Format("02/01/2006 15:04"), c.Message))
+		}
+	}
+
+	s.WriteString("\nUndo cannot restore anything gc removes.")
+	return s.String()
+}
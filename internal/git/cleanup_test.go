@@ -0,0 +1,126 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// finalizeBesideOtherBranch creates checkpoints on main and on another branch,
+// finalizes main and returns the other branch's checkpoint refs and the
+// commits of the finalized checkpoints
+func finalizeBesideOtherBranch(r *testRepo) (otherRefs string, finalized []string) {
+	r.t.Helper()
+	r.git(r.dir, "checkout", "-q", "-b", "other")
+	r.checkpoint("other\n")
+	otherRefs = r.git(r.dir, "for-each-ref", "--format=%(refname) %(objectname)", CheckpointRefPrefix+"other/")
+	r.git(r.dir, "checkout", "-q", "main")
+
+	r.checkpoint("one\n")
+	r.checkpoint("two\n")
+	finalized = strings.Fields(r.git(r.dir, "rev-parse", "HEAD^", "HEAD"))
+
+	if _, err := r.FinalizeAndPushWithMessage("Ship it"); err != nil {
+		r.t.Fatalf("finalize failed: %v", err)
+	}
+	return otherRefs, finalized
+}
+
+func TestFinalizeDropsOnlyFinalizedCheckpoints(t *testing.T) {
+	r := newTestRepo(t)
+	reflogBefore := r.git(r.dir, "reflog", "--format=%H")
+	otherRefs, finalized := finalizeBesideOtherBranch(r)
+
+	if refs := r.git(r.dir, "for-each-ref", CheckpointRefPrefix+"main/"); refs != "" {
+		t.Errorf("finalized checkpoint refs left:\n%s", refs)
+	}
+	if got := r.git(r.dir, "for-each-ref", "--format=%(refname) %(objectname)", CheckpointRefPrefix+"other/"); got != otherRefs {
+		t.Errorf("checkpoints of another branch changed:\n got %s\nwant %s", got, otherRefs)
+	}
+
+	// The reflog still reaches everything it did, the finalized checkpoints included
+	reflog := r.git(r.dir, "reflog", "--format=%H")
+	if !strings.HasSuffix(reflog, reflogBefore) {
+		t.Errorf("reflog entries from before finalize were expired:\n%s", reflog)
+	}
+	for _, commit := range finalized {
+		if !strings.Contains(reflog, commit) {
+			t.Errorf("finalized checkpoint %s no longer in the reflog", commit)
+		}
+	}
+}
+
+func TestFinalizeKeepsCheckpointsDuringRetention(t *testing.T) {
+	r := newTestRepo(t)
+	setConfig(t, "finalize.retention", "7d")
+	otherRefs, finalized := finalizeBesideOtherBranch(r)
+
+	kept := r.git(r.dir, "for-each-ref", "--format=%(objectname)", FinalizedRefPrefix)
+	if got := strings.Fields(kept); len(got) != 2 || !strings.Contains(kept, finalized[0]) || !strings.Contains(kept, finalized[1]) {
+		t.Errorf("kept finalized checkpoints %v, want %v", got, finalized)
+	}
+	if got := r.git(r.dir, "for-each-ref", "--format=%(refname) %(objectname)", CheckpointRefPrefix+"other/"); got != otherRefs {
+		t.Errorf("checkpoints of another branch changed:\n got %s\nwant %s", got, otherRefs)
+	}
+
+	plan, err := r.PlanGC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.ExpiredCheckpoints) != 0 {
+		t.Errorf("checkpoints within retention planned for removal: %v", plan.ExpiredCheckpoints)
+	}
+}
+
+func TestPlanGCOnlyPreviews(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("lost\n")
+	lost := r.git(r.dir, "rev-parse", "--short", "HEAD")
+	r.git(r.dir, "update-ref", "-d", r.git(r.dir, "for-each-ref", "--format=%(refname)", CheckpointRefPrefix))
+	r.git(r.dir, "reset", "-q", "--hard", "HEAD^")
+	reflogBefore := r.git(r.dir, "reflog", "--all", "--format=%H")
+
+	plan, err := r.PlanGC()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, c := range plan.UnreachableCommits {
+		found = found || c.Hash == lost
+	}
+	if !found || plan.ReflogEntries == 0 {
+		t.Errorf("plan = %+v, want %s unreachable and the reflog to expire", plan, lost)
+	}
+	if got := r.git(r.dir, "reflog", "--all", "--format=%H"); got != reflogBefore {
+		t.Error("previewing gc changed the reflog")
+	}
+	r.git(r.dir, "cat-file", "-e", lost)
+}
+
+func TestPlanGCPassesCommitsOnStdin(t *testing.T) {
+	fake := NewFakeRunner()
+	repo := &Repo{Runner: fake}
+
+	var fsck strings.Builder
+	for i := 0; i < 50000; i++ {
+		fsck.WriteString(fmt.Sprintf("unreachable commit %040x\n", i))
+	}
+	fake.Respond(Result{Stdout: fsck.String()}, "fsck")
+
+	plan, err := repo.PlanGC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.UnreachableObjects != 50000 {
+		t.Errorf("unreachable objects = %d, want 50000", plan.UnreachableObjects)
+	}
+	for i, inv := range fake.Calls() {
+		if inv.Args[0] != "log" || inv.Args[1] != "--no-walk" {
+			continue
+		}
+		if len(inv.Args) != 4 || strings.Count(fake.Stdin(i), "\n") != 50000 {
+			t.Errorf("log invoked with %d arguments and %d bytes on stdin, want the commits on stdin", len(inv.Args), len(fake.Stdin(i)))
+		}
+	}
+}
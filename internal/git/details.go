@@ -0,0 +1,80 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// LoadCheckpointDetails fills in the author, author and committer dates and
+// change counts of the given checkpoints with a single git call. Shadow
+// checkpoints are measured against their first parent.
+func (r *Repo) LoadCheckpointDetails(checkpoints []models.Checkpoint) error {
+	var commits []string
+	for _, cp := range checkpoints {
+		commits = append(commits, checkpointCommit(cp))
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+
+	args := []string{"log", "--no-walk=unsorted", "-m", "--first-parent", "--numstat",
+		"--format=" + recordSep + "%H" + fieldSep + "%an" + fieldSep + "%at" + fieldSep + "%ct"}
+	output, err := r.RunCommand(append(args, commits...)...)
+	if err != nil {
+		return fmt.Errorf("failed to read checkpoint details: %v", err)
+	}
+
+	details := make(map[string]models.Checkpoint)
+	for _, record := range strings.Split(output, recordSep) {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		header := strings.Split(lines[0], fieldSep)
+		if len(header) < 4 {
+			continue
+		}
+
+		detail := models.Checkpoint{
+			SHA:        header[0],
+			Author:     header[1],
+			AuthorTime: parseUnix(header[2]),
+			Time:       parseUnix(header[3]),
+		}
+		// numstat lines are "<added>\t<deleted>\t<path>"; binary files show "-"
+		for _, line := range lines[1:] {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) < 3 {
+				continue
+			}
+			detail.FilesChanged++
+			added, _ := strconv.Atoi(fields[0])
+			deleted, _ := strconv.Atoi(fields[1])
+			detail.Insertions += added
+			detail.Deletions += deleted
+		}
+		details[detail.SHA] = detail
+	}
+
+	for i := range checkpoints {
+		commit := checkpointCommit(checkpoints[i])
+		for sha, detail := range details {
+			if sha != commit && !strings.HasPrefix(sha, commit) {
+				continue
+			}
+			cp := &checkpoints[i]
+			cp.SHA = sha
+			cp.Author, cp.AuthorTime, cp.Time = detail.Author, detail.AuthorTime, detail.Time
+			cp.FilesChanged, cp.Insertions, cp.Deletions = detail.FilesChanged, detail.Insertions, detail.Deletions
+			break
+		}
+	}
+	return nil
+}
+
+// checkpointCommit returns the most precise name known for a checkpoint's commit
+func checkpointCommit(cp models.Checkpoint) string {
+	if cp.SHA != "" {
+		return cp.SHA
+	}
+	return cp.Hash
+}
@@ -0,0 +1,130 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// DiffMode selects how much of a diff is shown
+type DiffMode int
+
+const (
+	DiffPatch DiffMode = iota
+	DiffStat
+	DiffNameOnly
+)
+
+// DiffCheckpoints compares two checkpoints. from defaults to the checkpoint
+// the user is on (or the newest one), and to defaults to the working tree,
+// untracked files included. Either side may also be any commit git knows.
+func (r *Repo) DiffCheckpoints(from, to string, mode DiffMode) (*models.Diff, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+
+	diff := &models.Diff{}
+	var fromRev, toRev string
+	var err error
+
+	if from == "" {
+		fromRev, diff.From, err = r.defaultDiffBase()
+	} else {
+		fromRev, diff.From, err = r.resolveDiffEndpoint(from)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if to == "" {
+		// Snapshot through a temporary index so untracked files show up too
+		if toRev, err = r.snapshotWorktreeTree(); err != nil {
+			return nil, err
+		}
+		diff.To = "working tree"
+	} else if toRev, diff.To, err = r.resolveDiffEndpoint(to); err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff"}
+	switch mode {
+	case DiffStat:
+		args = append(args, "--stat")
+	case DiffNameOnly:
+		args = append(args, "--name-only")
+	}
+	args = append(args, fromRev, toRev)
+
+	// Keep the output's leading alignment, which RunCommand would trim
+	result, err := r.Exec(r.commandContext(), Invocation{Args: args})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff %s against %s: %v", diff.From, diff.To, err)
+	}
+	diff.Output = strings.TrimRight(result.Stdout, "\n")
+	return diff, nil
+}
+
+// defaultDiffBase returns the checkpoint the user is on, falling back to the
+// newest checkpoint and then to HEAD
+func (r *Repo) defaultDiffBase() (rev, label string, err error) {
+	checkpoints, err := r.GetCheckpoints()
+	if err != nil {
+		return "", "", err
+	}
+
+	current, _ := r.GetCurrentCheckpointHash()
+	var newest *models.Checkpoint
+	for i, cp := range checkpoints {
+		if cp.ID == "" {
+			continue
+		}
+		if cp.Hash == current {
+			return cp.Hash, describeDiffCheckpoint(cp), nil
+		}
+		if newest == nil {
+			newest = &checkpoints[i]
+		}
+	}
+	if newest != nil {
+		return newest.Hash, describeDiffCheckpoint(*newest), nil
+	}
+
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return "", "", ErrNoCheckpoints
+	}
+	return "HEAD", "HEAD", nil
+}
+
+// resolveDiffEndpoint resolves a checkpoint hash or id, or any other commit
+func (r *Repo) resolveDiffEndpoint(ref string) (rev, label string, err error) {
+	if cp, err := r.FindCheckpoint(ref); err == nil {
+		return cp.Hash, describeDiffCheckpoint(*cp), nil
+	}
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", ref+"^{commit}"); err == nil {
+		return ref, ref, nil
+	}
+	// ParentRev hands out the empty tree for root commits
+	if tree, err := r.emptyTree(); err == nil && ref == tree {
+		return ref, "empty tree", nil
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrCheckpointNotFound, ref)
+}
+
+// ParentRev returns a revision for the parent of commit to diff against. A
+// root commit has none, so the empty tree is returned instead.
+func (r *Repo) ParentRev(commit string) (string, error) {
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", commit+"^"); err == nil {
+		return commit + "^", nil
+	}
+	return r.emptyTree()
+}
+
+// emptyTree returns the id of the tree without any entries
+func (r *Repo) emptyTree() (string, error) {
+	return r.RunCommand("hash-object", "-t", "tree", "/dev/null")
+}
+
+// describeDiffCheckpoint labels a checkpoint in diff headers
+func describeDiffCheckpoint(cp models.Checkpoint) string {
+	return fmt.Sprintf("[%s] %s", cp.Hash, cp.Message)
+}
@@ -0,0 +1,28 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffRootCommitAgainstEmptyTree(t *testing.T) {
+	r := newTestRepo(t)
+	root := r.git(r.dir, "rev-parse", "--short", "HEAD")
+
+	from, err := r.ParentRev(root)
+	if err != nil {
+		t.Fatalf("resolving the parent of a root commit: %v", err)
+	}
+	diff, err := r.DiffCheckpoints(from, root, DiffPatch)
+	if err != nil {
+		t.Fatalf("diffing a root commit: %v", err)
+	}
+	if diff.From != "empty tree" || !strings.Contains(diff.Output, "+hello") {
+		t.Errorf("diff from %q:\n%s", diff.From, diff.Output)
+	}
+
+	r.checkpoint("one\n")
+	if from, err := r.ParentRev("HEAD"); err != nil || from != "HEAD^" {
+		t.Errorf("r.ParentRev(HEAD) = %q, %v, want HEAD^", from, err)
+	}
+}
@@ -0,0 +1,195 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// PlannedCommand is a git invocation recorded during a dry run
+type PlannedCommand struct {
+	Invocation
+	// Executed is true for commands that only write unreachable objects
+	// (e.g. commit-tree); they run so later steps see real hashes
+	Executed bool
+}
+
+// DryRunRunner runs read-only git commands and records every command that
+// would change refs, HEAD, the index, the working tree, config or a remote.
+// Recorded commands succeed with empty output without being executed.
+type DryRunRunner struct {
+	Runner Runner
+
+	mu   sync.Mutex
+	plan []PlannedCommand
+}
+
+// Run executes read-only invocations and records mutating ones
+func (d *DryRunRunner) Run(ctx context.Context, inv Invocation) (Result, error) {
+	switch classifyCommand(inv) {
+	case commandReadOnly:
+		return d.Runner.Run(ctx, inv)
+	case commandObjectsOnly:
+		result, err := d.Runner.Run(ctx, inv)
+		d.record(PlannedCommand{Invocation: inv, Executed: true})
+		return result, err
+	}
+
+	d.record(PlannedCommand{Invocation: inv})
+	return Result{}, nil
+}
+
+// Plan returns the commands recorded so far, in order
+func (d *DryRunRunner) Plan() []PlannedCommand {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]PlannedCommand(nil), d.plan...)
+}
+
+// Reset clears the recorded plan
+func (d *DryRunRunner) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.plan = nil
+}
+
+func (d *DryRunRunner) record(cmd PlannedCommand) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.plan = append(d.plan, cmd)
+}
+
+// IsDryRun reports whether git commands are currently only being planned.
+// Operations check it before writing vibe-check's own files.
+func (r *Repo) IsDryRun() bool {
+	_, ok := r.Runner.(*DryRunRunner)
+	return ok
+}
+
+// FormatPlan renders a dry-run plan as a numbered list of git commands
+func FormatPlan(plan []PlannedCommand) string {
+	if len(plan) == 0 {
+		return "No git commands would change the repository"
+	}
+
+	var s strings.Builder
+	for i, cmd := range plan {
+		line := fmt.Sprintf("%2d. %s", i+1, FormatCommand(cmd.Args))
+		if cmd.Executed {
+			line += "   (objects only, executed)"
+		}
+		s.WriteString(line + "\n")
+	}
+	return strings.TrimRight(s.String(), "\n")
+}
+
+type commandKind int
+
+const (
+	commandMutating commandKind = iota
+	commandReadOnly
+	commandObjectsOnly
+)
+
+// readOnlyCommands never change repository state
+var readOnlyCommands = map[string]bool{
+	"rev-parse": true, "rev-list": true, "log": true, "show": true, "status": true,
+	"diff": true, "diff-tree": true, "diff-index": true, "diff-files": true,
+	"for-each-ref": true, "show-ref": true, "cat-file": true, "ls-files": true,
+	"ls-tree": true, "merge-base": true, "describe": true, "name-rev": true,
+	"shortlog": true, "blame": true, "grep": true, "fsck": true, "count-objects": true,
+	"var": true, "check-ignore": true, "check-ref-format": true, "version": true,
+}
+
+// objectCommands only add objects to the database; they never move refs
+var objectCommands = map[string]bool{
+	"write-tree": true, "commit-tree": true, "hash-object": true, "mktree": true,
+}
+
+// scratchIndexCommands are harmless when they operate on a temporary index
+var scratchIndexCommands = map[string]bool{
+	"add": true, "read-tree": true, "update-index": true, "apply": true, "rm": true,
+}
+
+// classifyCommand decides whether an invocation may run during a dry run
+func classifyCommand(inv Invocation) commandKind {
+	if len(inv.Args) == 0 {
+		return commandReadOnly
+	}
+	sub, rest := inv.Args[0], inv.Args[1:]
+
+	if usesScratchIndex(inv) && scratchIndexCommands[sub] && !hasAnyArg(rest, "-u") {
+		return commandReadOnly
+	}
+
+	switch {
+	case readOnlyCommands[sub]:
+		return commandReadOnly
+	case objectCommands[sub]:
+		return commandObjectsOnly
+	}
+
+	switch sub {
+	case "reflog":
+		// `git reflog [show]` reads; expire/delete rewrite reflogs
+		if len(rest) == 0 || strings.HasPrefix(rest[0], "-") || rest[0] == "show" {
+			return commandReadOnly
+		}
+	case "config":
+		if hasAnyArg(rest, "--get", "--get-all", "--get-regexp", "--list", "-l") {
+			return commandReadOnly
+		}
+	case "branch":
+		if hasAnyArg(rest, "--list", "--show-current", "--contains", "--merged", "--no-merged", "--points-at") &&
+			!hasAnyArg(rest, "-d", "-D", "--delete", "-m", "-M", "-c", "-C", "-f", "--force") {
+			return commandReadOnly
+		}
+	case "symbolic-ref":
+		// Reading HEAD passes a single ref name; writing passes two
+		if len(positionalArgs(rest)) <= 1 && !hasAnyArg(rest, "-d", "--delete") {
+			return commandReadOnly
+		}
+	case "remote":
+		if len(rest) == 0 || rest[0] == "-v" || rest[0] == "show" || rest[0] == "get-url" {
+			return commandReadOnly
+		}
+	case "stash":
+		if len(rest) > 0 && (rest[0] == "list" || rest[0] == "show") {
+			return commandReadOnly
+		}
+	}
+
+	return commandMutating
+}
+
+// usesScratchIndex reports whether an invocation targets a temporary index file
+func usesScratchIndex(inv Invocation) bool {
+	for _, env := range inv.Env {
+		if strings.HasPrefix(env, "GIT_INDEX_FILE=") {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyArg(args []string, flags ...string) bool {
+	for _, arg := range args {
+		for _, flag := range flags {
+			if arg == flag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func positionalArgs(args []string) []string {
+	var positional []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+		}
+	}
+	return positional
+}
@@ -0,0 +1,139 @@
+package git
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// FakeRunner is a Runner for tests. It records every invocation and replies
+// with scripted results matched by argument prefix, so operations such as
+// FinalizeAndPushWithMessage can be exercised without a real repository.
+//
+//	fake := git.NewFakeRunner()
+//	fake.Respond(git.Result{Stdout: "main"}, "rev-parse", "--abbrev-ref", "HEAD")
+//	fake.Fail(1, "rejected", "push")
+//	repo := &git.Repo{Runner: fake}
+type FakeRunner struct {
+	mu        sync.Mutex
+	calls     []Invocation
+	stdin     []string
+	responses []fakeResponse
+
+	// Default is returned for invocations without a scripted response
+	Default Result
+}
+
+type fakeResponse struct {
+	prefix []string
+	result Result
+	err    error
+}
+
+// NewFakeRunner returns a FakeRunner that succeeds with empty output by default
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// Respond scripts a successful result for invocations starting with args.
+// Later scripts take precedence over earlier ones, and longer prefixes over shorter.
+func (f *FakeRunner) Respond(result Result, args ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, fakeResponse{prefix: args, result: result})
+}
+
+// Fail scripts a non-zero exit for invocations starting with args
+func (f *FakeRunner) Fail(exitCode int, stderr string, args ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, fakeResponse{
+		prefix: args,
+		result: Result{Stderr: stderr, ExitCode: exitCode},
+		err:    &CommandError{Args: args, ExitCode: exitCode, Stderr: stderr},
+	})
+}
+
+// Run records the invocation and returns the best matching scripted result
+func (f *FakeRunner) Run(ctx context.Context, inv Invocation) (Result, error) {
+	var input string
+	if inv.Stdin != nil {
+		data, _ := io.ReadAll(inv.Stdin)
+		input = string(data)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, inv)
+	f.stdin = append(f.stdin, input)
+
+	if err := ctx.Err(); err != nil {
+		return Result{ExitCode: -1}, err
+	}
+
+	best := -1
+	for i, resp := range f.responses {
+		if !hasPrefix(inv.Args, resp.prefix) {
+			continue
+		}
+		if best == -1 || len(resp.prefix) >= len(f.responses[best].prefix) {
+			best = i
+		}
+	}
+	if best == -1 {
+		return f.Default, nil
+	}
+	return f.responses[best].result, f.responses[best].err
+}
+
+// Calls returns every invocation seen so far, in order
+func (f *FakeRunner) Calls() []Invocation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Invocation(nil), f.calls...)
+}
+
+// Commands returns the recorded invocations as "git ..." command lines
+func (f *FakeRunner) Commands() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	commands := make([]string, len(f.calls))
+	for i, inv := range f.calls {
+		commands[i] = FormatCommand(inv.Args)
+	}
+	return commands
+}
+
+// Stdin returns what was written to standard input of the i-th invocation
+func (f *FakeRunner) Stdin(i int) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if i < 0 || i >= len(f.stdin) {
+		return ""
+	}
+	return f.stdin[i]
+}
+
+// Ran reports whether an invocation starting with args was recorded
+func (f *FakeRunner) Ran(args ...string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, inv := range f.calls {
+		if hasPrefix(inv.Args, args) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasPrefix(args, prefix []string) bool {
+	if len(prefix) > len(args) {
+		return false
+	}
+	for i := range prefix {
+		if args[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,218 +1,350 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"strings"
-	"time"
 	"vibe-check/internal/models"
+	"vibe-check/internal/scan"
 )
 
 // FinalizeAndPush squashes consecutive checkpoints and pushes to remote
-func FinalizeAndPush() error {
-	return FinalizeAndPushWithMessage("")
+func (r *Repo) FinalizeAndPush() error {
+	_, err := r.FinalizeAndPushWithMessage("")
+	return err
 }
 
-// FinalizeAndPushWithMessage squashes consecutive checkpoints and pushes to remote with custom message
-func FinalizeAndPushWithMessage(customMessage string) error {
-	if !IsRepo() {
-		return fmt.Errorf("not in a Git repository")
+// FinalizeAndPushWithMessage squashes consecutive checkpoints and pushes to remote with custom message.
+// It returns the plan that was carried out. If any step fails, including the
+// push, the repository is rolled back and a *FinalizeError describes what was restored.
+func (r *Repo) FinalizeAndPushWithMessage(customMessage string) (*models.FinalizePlan, error) {
+	return r.finalize(customMessage, true)
+}
+
+// SquashCheckpoints squashes consecutive checkpoints into a single local commit
+// without pushing. It returns the plan that was carried out.
+func (r *Repo) SquashCheckpoints(customMessage string) (*models.FinalizePlan, error) {
+	return r.finalize(customMessage, false)
+}
+
+// finalize runs the squash, and optionally the push, as one transaction.
+// The backup branch is only deleted once everything succeeded.
+func (r *Repo) finalize(customMessage string, push bool) (*models.FinalizePlan, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
 	}
 
-	// Check if we're in detached HEAD state and fix it
-	detectedBranch, branchErr := GetCurrentBranch()
-	if branchErr == nil && detectedBranch == "HEAD" {
-		// We're in detached HEAD - switch back to main/master branch
-		_, err := RunCommand("checkout", "main")
+	// Catch a bad message template before anything is changed
+	if customMessage == "" {
+		if err := checkMessageTemplates(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Look for secrets and large files in what would be pushed, before anything changes
+	var findings []models.Finding
+	if push {
+		pending, err := r.GetFinalizePlan()
 		if err != nil {
-			// Try master if main doesn't exist
-			_, err = RunCommand("checkout", "master")
-			if err != nil {
-				return fmt.Errorf("in detached HEAD state and cannot switch to main/master branch. Please checkout a branch first: %v", err)
-			}
+			return nil, err
+		}
+		if findings, err = r.ScanPush(pending); err != nil {
+			return nil, err
 		}
 	}
 
-	// Get current commit hash
-	currentCommit, err := GetCurrentCommit()
+	op := r.beginOperation(OpFinalize)
+	tx, err := r.beginFinalizeTx()
 	if err != nil {
-		return fmt.Errorf("error getting current commit: %v", err)
+		return nil, err
 	}
 
-	// Get all checkpoints from reflog to include navigation history
-	checkpoints, err := GetCheckpointsFromReflog()
+	plan, step, err := r.squashCheckpoints(customMessage)
 	if err != nil {
-		return fmt.Errorf("error getting checkpoints: %v", err)
+		return nil, tx.rollback(step, err)
 	}
 
-	if len(checkpoints) == 0 {
-		return fmt.Errorf("no checkpoints found")
+	if push {
+		if err := r.pushTarget(plan.Target); err != nil {
+			return nil, tx.rollback("pushing", err)
+		}
 	}
 
-	// Find current position in checkpoint list
-	var currentIndex = -1
-	for i, cp := range checkpoints {
-		if cp.Hash == currentCommit {
-			currentIndex = i
-			break
+	if err := tx.commit(); err != nil {
+		return plan, err
+	}
+	op.finish(fmt.Sprintf("finalize %d checkpoint(s) on %s", len(plan.Squash), plan.Target.Branch))
+	plan.Findings = findings
+	return plan, nil
+}
+
+// squashCheckpoints carries out the squash. On failure it also returns the
+// step that failed; the caller rolls back.
+func (r *Repo) squashCheckpoints(customMessage string) (*models.FinalizePlan, string, error) {
+	// Check if we're in detached HEAD state and fix it
+	detectedBranch, branchErr := r.GetCurrentBranch()
+	if branchErr == nil && detectedBranch == "HEAD" {
+		// We're in detached HEAD - move the target branch onto this checkpoint
+		target, err := r.ResolvePushTarget()
+		if err != nil {
+			return nil, "resolving the target branch", err
+		}
+		if err := r.attachBranchAtHead(target.Branch); err != nil {
+			return nil, "attaching the target branch", err
 		}
 	}
 
-	if currentIndex == -1 {
-		return fmt.Errorf("current commit is not a checkpoint. Push feature only works from checkpoints")
+	plan, err := r.GetFinalizePlan()
+	if err != nil {
+		return nil, "planning", err
+	}
+	current := plan.Squash[0]
+	baseCommit := plan.BaseCommit
+
+	// Decide up front whether squashing leaves anything to commit, so the
+	// plan does not depend on repository state after the reset
+	stageWorkingTree := false
+	if r.checkpointsMatchBase(plan) {
+		// Checkpoints match the base - only uncommitted work could be committed
+		if !r.HasUncommittedChanges() {
+			return nil, "planning", fmt.Errorf("no changes to commit after squashing checkpoints. This usually means:\n" +
+				"1. All checkpoints had identical content to the base commit\n" +
+				"2. The soft reset resulted in no differences\n" +
+				"Solution: Your checkpoints have been consolidated - no new commit was needed")
+		}
+		stageWorkingTree = true
 	}
 
-	// Find consecutive checkpoints going backwards from current position
-	consecutiveCheckpoints := []models.Checkpoint{checkpoints[currentIndex]}
-	
-	// Go backwards and collect consecutive checkpoints
-	for i := currentIndex + 1; i < len(checkpoints); i++ {
-		// Check if this is still a checkpoint (has CHECKPOINT: prefix)
-		if !strings.Contains(checkpoints[i].Message, "CHECKPOINT:") {
-			break
+	// Soft reset to base commit to preserve changes but remove checkpoint commits
+	if _, err := r.RunCommand("reset", "--soft", baseCommit); err != nil {
+		return nil, "resetting to the base commit", err
+	}
+
+	// Shadow checkpoints never touched the index - stage the snapshot itself
+	if current.Shadow {
+		if _, err := r.RunCommand("read-tree", current.Hash); err != nil {
+			return nil, "staging the shadow checkpoint", err
 		}
-		consecutiveCheckpoints = append(consecutiveCheckpoints, checkpoints[i])
 	}
 
-	// Find the commit before the oldest checkpoint we're squashing
-	var baseCommit string
-	if currentIndex + len(consecutiveCheckpoints) < len(checkpoints) {
-		// There's a commit before our checkpoints
-		baseCommit = checkpoints[currentIndex + len(consecutiveCheckpoints)].Hash
-	} else {
-		// We're at the very beginning, find first non-checkpoint commit
-		output, err := RunCommand("log", "--oneline", "--format=%H %s")
-		if err != nil {
-			return fmt.Errorf("error getting commit history: %v", err)
+	// Generate commit message (custom or from the finalize template)
+	commitMessage := customMessage
+	if commitMessage == "" {
+		var err error
+		if commitMessage, err = r.finalizeMessage(plan); err != nil {
+			return nil, "rendering the commit message", err
 		}
+	}
 
-		lines := strings.Split(output, "\n")
-		for _, line := range lines {
-			if !strings.Contains(line, "CHECKPOINT:") {
-				parts := strings.SplitN(line, " ", 2)
-				if len(parts) >= 1 {
-					baseCommit = parts[0]
-					break
-				}
-			}
+	// Working directory has changes but nothing would be staged - stage them
+	if stageWorkingTree {
+		if _, err := r.RunCommand("add", "-A", "--", r.scopePathspec()); err != nil {
+			return nil, "staging changes", err
 		}
 	}
 
-	if baseCommit == "" {
-		return fmt.Errorf("cannot find base commit for squashing. All commits appear to be checkpoints")
+	// Create the final commit
+	output, err := r.RunCommand("commit", "-m", commitMessage)
+	if err != nil {
+		diagnosis := diagnoseCommitError(output, err)
+		return nil, "creating the final commit", fmt.Errorf("%s\n\nDiagnosis: %s", err, diagnosis)
 	}
-	
 
-	// Remove any checkpoints newer than current position (if any)
-	var removedNewer int
-	for i := 0; i < currentIndex; i++ {
-		removedNewer++
+	// Retire the refs of every checkpoint that was squashed or discarded
+	if err := r.retireCheckpoints(append(plan.Squash, plan.Drop...)); err != nil {
+		return nil, "cleaning up checkpoint refs", err
 	}
 
-	// Create backup branch
-	backupBranch := fmt.Sprintf("vibe-check-backup-%d", time.Now().Unix())
-	_, err = RunCommand("branch", backupBranch, "HEAD")
+	return plan, "", nil
+}
+
+// finalizeMessage renders the finalize template for a plan. It runs after
+// the soft reset, so the squashed changes are compared with the last checkpoint.
+func (r *Repo) finalizeMessage(plan *models.FinalizePlan) (string, error) {
+	data, err := newMessageData(plan.Target.Branch, "")
 	if err != nil {
-		return fmt.Errorf("failed to create backup: %v", err)
+		return "", err
+	}
+	current := plan.Squash[0].Hash
+	data.files = func() ([]string, error) {
+		output, err := r.RunCommand("diff", "--name-only", plan.BaseCommit, current)
+		return splitLines(output), err
+	}
+	data.diffStat = func() (string, error) {
+		return r.RunCommand("diff", "--shortstat", plan.BaseCommit, current)
+	}
+	data.count = func() (int, error) {
+		return len(plan.Squash), nil
 	}
 
-	// Soft reset to base commit to preserve changes but remove checkpoint commits
-	_, err = RunCommand("reset", "--soft", baseCommit)
-	if err != nil {
-		// Restore backup on failure
-		RunCommand("reset", "--hard", backupBranch)
-		RunCommand("branch", "-D", backupBranch)
-		return fmt.Errorf("failed to reset to base: %v", err)
+	message, err := renderMessage(configFinalizeTemplate, data)
+	if err == nil && message == "" {
+		err = fmt.Errorf("%s rendered an empty message", configFinalizeTemplate)
 	}
+	return message, err
+}
 
-	// Generate commit message (custom or automatic)
-	var commitMessage string
-	if customMessage != "" {
-		commitMessage = customMessage
-	} else {
-		// Use timestamp-based auto message
-		timestamp := GetTimestamp()
-		commitMessage = fmt.Sprintf("Update: %s", timestamp)
-	}
-
-	// Check if there are changes to commit after soft reset
-	// Use --cached to check staged changes specifically
-	stagedStatus, statusErr := RunCommand("diff", "--cached", "--name-only")
-	if statusErr == nil && strings.TrimSpace(stagedStatus) == "" {
-		// No staged changes after soft reset - check if working directory has changes
-		workingStatus, _ := RunCommand("status", "--porcelain")
-		if strings.TrimSpace(workingStatus) == "" {
-			// No changes at all - this means checkpoints were identical to base
-			RunCommand("branch", "-D", backupBranch)
-			return fmt.Errorf("no changes to commit after squashing checkpoints. This usually means:\n" +
-				"1. All checkpoints had identical content to the base commit\n" +
-				"2. The soft reset resulted in no differences\n" +
-				"Solution: Your checkpoints have been consolidated - no new commit was needed")
-		} else {
-			// Working directory has changes but nothing staged - stage them
-			RunCommand("add", ".")
+// attachBranchAtHead points branch at the detached HEAD and checks it out,
+// keeping the working tree. It refuses when that would drop commits on the
+// branch that are not checkpoints.
+func (r *Repo) attachBranchAtHead(branch string) error {
+	output, err := r.RunCommand("log", "--format=%h"+fieldSep+"%B"+recordSep, "HEAD..refs/heads/"+branch)
+	if err != nil {
+		return fmt.Errorf("cannot compare HEAD with branch %s: %v", branch, err)
+	}
+	var lost int
+	for _, fields := range splitRecords(output) {
+		if len(fields) >= 2 && !IsCheckpointMessage(fields[1]) {
+			lost++
 		}
 	}
+	if lost > 0 {
+		return fmt.Errorf("branch %s has %d commit(s) after this checkpoint that are not checkpoints; finalizing here would drop them. "+
+			"Run `vibe-check return` to go back to %s first", branch, lost, branch)
+	}
 
-	// Create the final commit
-	output, err := RunCommand("commit", "-m", commitMessage)
+	if _, err := r.RunCommand("checkout", "-B", branch); err != nil {
+		return fmt.Errorf("in detached HEAD state and cannot move %s branch here. Please checkout a branch first: %v", branch, err)
+	}
+	return r.clearOriginBranch()
+}
+
+// checkpointsMatchBase reports whether the squashed checkpoints change nothing
+// since the base, in which case finalize commits the uncommitted work instead
+func (r *Repo) checkpointsMatchBase(plan *models.FinalizePlan) bool {
+	changes, err := r.RunCommand("diff", "--name-only", plan.BaseCommit, plan.Squash[0].Hash)
+	return err == nil && strings.TrimSpace(changes) == ""
+}
+
+// ScanPush scans what finalizing plan would push: every file that differs
+// from the remote branch, plus the uncommitted changes finalize commits when
+// the checkpoints match the base. In block mode findings come back as a *scan.Error.
+func (r *Repo) ScanPush(plan *models.FinalizePlan) ([]models.Finding, error) {
+	return r.runScan("push", func() ([]scan.File, error) {
+		files, err := r.commitFiles(r.remoteTip(plan.Target), plan.Squash[0].Hash)
+		if err != nil || !r.checkpointsMatchBase(plan) {
+			return files, err
+		}
+		uncommitted, err := r.worktreeFiles([]string{r.scopePathspec()})
+		if err != nil {
+			return nil, err
+		}
+		return mergeFiles(files, uncommitted), nil
+	})
+}
+
+// scanTarget scans what pushing the branch of target would send
+func (r *Repo) scanTarget(target models.PushTarget) ([]models.Finding, error) {
+	return r.runScan("push", func() ([]scan.File, error) {
+		return r.commitFiles(r.remoteTip(target), "refs/heads/"+target.Branch)
+	})
+}
+
+// remoteTip returns the commit the remote branch of target was at when last
+// fetched, or "" when it is unknown and everything would be new
+func (r *Repo) remoteTip(target models.PushTarget) string {
+	tip, err := r.RunCommand("rev-parse", "--verify", "-q", "refs/remotes/"+target.Remote+"/"+target.RemoteBranch+"^{commit}")
 	if err != nil {
-		// Restore backup on failure
-		RunCommand("reset", "--hard", backupBranch)
-		RunCommand("branch", "-D", backupBranch)
-		
-		diagnosis := diagnoseCommitError(output, err)
-		return fmt.Errorf("failed to create final commit:\n%s\n\nDiagnosis: %s", err, diagnosis)
+		return ""
 	}
+	return tip
+}
 
-	// Get current branch name for push
-	currentBranch, branchErr := GetCurrentBranch()
-	if branchErr != nil {
-		currentBranch = "main" // fallback to main if can't detect
+// Publish pushes the current branch to its push target with lease protection.
+// It returns what the scan before pushing warned about.
+func (r *Repo) Publish() ([]models.Finding, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
 	}
-	
-	// Push to remote (force with lease for safety when rewriting history)
-	pushOutput, err := RunCommand("push", "--force-with-lease", "origin", currentBranch)
+
+	branch, err := r.GetCurrentBranch()
 	if err != nil {
-		// Don't restore backup here - commit was successful, just push failed
-		RunCommand("branch", "-D", backupBranch)
-		
-		// Provide detailed error diagnosis
-		diagnosis := diagnosePushError(pushOutput, err)
-		return fmt.Errorf("commit created successfully but push failed:\nError: %s\nOutput: %s\n\nDiagnosis: %s\n\nNote: You can manually push with:\ngit push --force-with-lease origin %s", err, pushOutput, diagnosis, currentBranch)
+		return nil, fmt.Errorf("error getting current branch: %v", err)
+	}
+	if branch == "HEAD" {
+		return nil, ErrDetachedHead
 	}
 
-	// Clean up backup branch
-	RunCommand("branch", "-D", backupBranch)
+	target, err := r.ResolvePushTarget()
+	if err != nil {
+		return nil, err
+	}
+	return r.PublishTarget(target)
+}
 
-	// Clean up reflog ONLY after successful push
-	// This removes ALL checkpoints from the UI lists (including current)
-	RunCommand("reflog", "expire", "--expire=now", "--all")
-	RunCommand("gc", "--prune=now")
+// PublishTarget scans and pushes to a push target. It forces with lease because
+// finalize rewrites history, but refuses if the remote moved since last fetch.
+func (r *Repo) PublishTarget(target models.PushTarget) ([]models.Finding, error) {
+	findings, err := r.scanTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.pushTarget(target); err != nil {
+		return nil, fmt.Errorf("%v\n\nNote: You can push later with:\nvibe-check push\nor manually with:\n%s", err, manualPushCommand(target))
+	}
+	return findings, nil
+}
 
+// pushTarget runs the push and diagnoses a failure
+func (r *Repo) pushTarget(target models.PushTarget) error {
+	pushOutput, err := r.RunCommand("push", "--force-with-lease", target.Remote, target.Refspec)
+	if err != nil {
+		// Provide detailed error diagnosis
+		diagnosis := diagnosePushError(pushOutput, err, target)
+		return fmt.Errorf("push failed:\nError: %s\nOutput: %s\n\nDiagnosis: %s", err, pushOutput, diagnosis)
+	}
 	return nil
 }
 
-// GetFinalizeInfo returns information about what would be finalized
-func GetFinalizeInfo() (string, error) {
-	if !IsRepo() {
-		return "", fmt.Errorf("not in a Git repository")
+// CheckPush asks the remote whether pushing to target would be accepted,
+// without updating it. It catches a moved remote branch, missing access and
+// an unreachable remote, but not hooks that only run on a real push.
+func (r *Repo) CheckPush(target models.PushTarget) error {
+	output, err := r.RunCommand("push", "--dry-run", "--force-with-lease", target.Remote, target.Refspec)
+	if err != nil {
+		return fmt.Errorf("push would fail:\nError: %s\nOutput: %s\n\nDiagnosis: %s", err, output, diagnosePushError(output, err, target))
+	}
+	return nil
+}
+
+// manualPushCommand returns the git command that pushes target by hand
+func manualPushCommand(target models.PushTarget) string {
+	return FormatCommand([]string{"push", "--force-with-lease", target.Remote, target.Refspec})
+}
+
+// ErrDetachedHead is returned when pushing without a branch checked out
+var ErrDetachedHead = errors.New("in detached HEAD state. Please checkout a branch before pushing")
+
+// ErrNoCheckpoints is returned when the current branch has no checkpoints
+var ErrNoCheckpoints = errors.New("no checkpoints found")
+
+// ErrNotOnCheckpoint is returned when finalizing from a commit that is not a checkpoint
+var ErrNotOnCheckpoint = errors.New("current commit is not a checkpoint. Push feature only works from checkpoints")
+
+// GetFinalizePlan works out which checkpoints finalize would squash and drop,
+// the base commit, the push target and the combined diffstat
+func (r *Repo) GetFinalizePlan() (*models.FinalizePlan, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
 	}
 
-	currentCommit, err := GetCurrentCommit()
+	// Get current checkpoint hash (HEAD, or the shadow snapshot we are on)
+	currentCommit, err := r.GetCurrentCheckpointHash()
 	if err != nil {
-		return "", fmt.Errorf("error getting current commit: %v", err)
+		return nil, fmt.Errorf("error getting current commit: %v", err)
 	}
 
-	checkpoints, err := GetCheckpointsFromReflog()
+	// Get all checkpoints recorded for this branch
+	checkpoints, err := r.GetCheckpoints()
 	if err != nil {
-		return "", fmt.Errorf("error getting checkpoints: %v", err)
+		return nil, fmt.Errorf("error getting checkpoints: %v", err)
 	}
 
 	if len(checkpoints) == 0 {
-		return "No checkpoints found", nil
+		return nil, ErrNoCheckpoints
 	}
 
-	// Find current position
+	// Find current position in checkpoint list
 	var currentIndex = -1
 	for i, cp := range checkpoints {
 		if cp.Hash == currentCommit {
@@ -221,26 +353,78 @@ func GetFinalizeInfo() (string, error) {
 		}
 	}
 
-	if currentIndex == -1 {
-		return "Current commit is not a checkpoint", nil
+	if currentIndex == -1 || checkpoints[currentIndex].ID == "" {
+		return nil, ErrNotOnCheckpoint
+	}
+
+	plan := &models.FinalizePlan{
+		Squash: []models.Checkpoint{checkpoints[currentIndex]},
+		// Any checkpoints newer than the current position are discarded
+		Drop: append([]models.Checkpoint(nil), checkpoints[:currentIndex]...),
+	}
+
+	// Go backwards and collect consecutive checkpoints
+	for i := currentIndex + 1; i < len(checkpoints); i++ {
+		// Stop at the first entry that is not a recorded checkpoint
+		if checkpoints[i].ID == "" {
+			break
+		}
+		plan.Squash = append(plan.Squash, checkpoints[i])
+	}
+
+	// Find the commit before the oldest checkpoint we're squashing
+	var baseCommit string
+	if currentIndex + len(plan.Squash) < len(checkpoints) {
+		// There's a commit before our checkpoints
+		baseCommit = checkpoints[currentIndex + len(plan.Squash)].Hash
+	} else {
+		// We're at the very beginning, find first non-checkpoint commit
+		output, err := r.RunCommand("log", "--format=%H"+fieldSep+"%B"+recordSep)
+		if err != nil {
+			return nil, fmt.Errorf("error getting commit history: %v", err)
+		}
+
+		for _, fields := range splitRecords(output) {
+			if len(fields) >= 2 && !IsCheckpointMessage(fields[1]) {
+				baseCommit = fields[0]
+				break
+			}
+		}
+	}
+
+	if baseCommit == "" {
+		return nil, fmt.Errorf("cannot find base commit for squashing. All commits appear to be checkpoints")
+	}
+
+	base, err := r.RunCommand("log", "-1", "--format=%h"+fieldSep+"%s", baseCommit)
+	if err != nil {
+		return nil, fmt.Errorf("error reading base commit: %v", err)
 	}
+	plan.BaseCommit, plan.BaseMessage, _ = strings.Cut(base, fieldSep)
 
-	// Count consecutive checkpoints
-	consecutiveCount := 1
-	for i := currentIndex + 1; i < len(checkpoints) && strings.Contains(checkpoints[i].Message, "CHECKPOINT:"); i++ {
-		consecutiveCount++
+	// Keep the diffstat's leading alignment, which RunCommand would trim
+	if stat, err := r.Exec(r.commandContext(), Invocation{Args: []string{"diff", "--stat", plan.BaseCommit, plan.Squash[0].Hash}}); err == nil {
+		plan.DiffStat = strings.TrimRight(stat.Stdout, "\n")
+	}
+	plan.Target, err = r.ResolvePushTarget()
+	if err != nil {
+		return nil, err
 	}
 
+	return plan, nil
+}
+
+// FormatFinalizePlan renders a finalize plan for display
+func FormatFinalizePlan(plan *models.FinalizePlan) string {
 	var info strings.Builder
-	info.WriteString(fmt.Sprintf("Will squash %d consecutive checkpoints\n", consecutiveCount))
+	info.WriteString(fmt.Sprintf("Will squash %d consecutive checkpoints\n", len(plan.Squash)))
 	
-	if currentIndex > 0 {
-		info.WriteString(fmt.Sprintf("Will remove %d newer checkpoints\n", currentIndex))
+	if len(plan.Drop) > 0 {
+		info.WriteString(fmt.Sprintf("Will remove %d newer checkpoints\n", len(plan.Drop)))
 	}
 	
 	info.WriteString("\nCheckpoints to be squashed:\n")
-	for i := 0; i < consecutiveCount && currentIndex+i < len(checkpoints); i++ {
-		cp := checkpoints[currentIndex+i]
+	for i, cp := range plan.Squash {
 		marker := "  "
 		if i == 0 {
 			marker = "> " // current position
@@ -248,20 +432,53 @@ func GetFinalizeInfo() (string, error) {
 		info.WriteString(fmt.Sprintf("%s[%s] %s\n", marker, cp.Hash, cp.Message))
 	}
 
-	return info.String(), nil
+	if len(plan.Drop) > 0 {
+		info.WriteString("\nCheckpoints to be dropped:\n")
+		for _, cp := range plan.Drop {
+			info.WriteString(fmt.Sprintf("  [%s] %s\n", cp.Hash, cp.Message))
+		}
+	}
+
+	info.WriteString(fmt.Sprintf("\nBase commit: [%s] %s\n", plan.BaseCommit, plan.BaseMessage))
+	info.WriteString(fmt.Sprintf("Push target: %s/%s\n", plan.Target.Remote, plan.Target.RemoteBranch))
+	if plan.Target.Refspec != plan.Target.Branch {
+		info.WriteString(fmt.Sprintf("Refspec: %s\n", plan.Target.Refspec))
+	}
+
+	if plan.DiffStat != "" {
+		info.WriteString("\nChanges:\n" + plan.DiffStat + "\n")
+	}
+
+	return info.String()
+}
+
+// GetFinalizeInfo returns information about what would be finalized
+func (r *Repo) GetFinalizeInfo() (string, error) {
+	plan, err := r.GetFinalizePlan()
+	switch {
+	case errors.Is(err, ErrNoCheckpoints):
+		return "No checkpoints found", nil
+	case errors.Is(err, ErrNotOnCheckpoint):
+		return "Current commit is not a checkpoint", nil
+	case err != nil:
+		return "", err
+	}
+
+	return FormatFinalizePlan(plan), nil
 }
 
 
 // diagnosePushError analyzes push failure and provides helpful suggestions
-func diagnosePushError(output string, err error) string {
+func diagnosePushError(output string, err error, target models.PushTarget) string {
 	errorMsg := strings.ToLower(output + " " + err.Error())
+	manualPush := manualPushCommand(target)
 	
 	if strings.Contains(errorMsg, "not a full refname") || strings.Contains(errorMsg, "refs/heads") {
 		return "Git refname issue (HEAD push problem). Solutions:\n" +
 			"1. This should now be fixed - using branch name instead of HEAD\n" +
 			"2. Check current branch: git branch\n" +
 			"3. Make sure you're on a proper branch, not detached HEAD\n" +
-			"4. Try: git checkout -b main (if no branch exists)"
+			"4. Try: git checkout -b " + target.Branch + " (if no branch exists)"
 	}
 	
 	if strings.Contains(errorMsg, "permission denied") || strings.Contains(errorMsg, "authentication") {
@@ -269,23 +486,23 @@ func diagnosePushError(output string, err error) string {
 			"1. Check if you have push access to this repository\n" +
 			"2. Verify your Git credentials: git config --list | grep user\n" +
 			"3. For GitHub, check if you need a personal access token\n" +
-			"Manual push: git push --force-with-lease origin main"
+			"Manual push: " + manualPush
 	}
 	
 	if strings.Contains(errorMsg, "no such remote") || strings.Contains(errorMsg, "does not exist") {
 		return "Remote repository not found. Solutions:\n" +
 			"1. Check remote URL: git remote -v\n" +
-			"2. Add remote if missing: git remote add origin <your-repo-url>\n" +
-			"3. Update remote URL: git remote set-url origin <correct-url>\n" +
-			"Manual push: git push --force-with-lease origin main"
+			"2. Add remote if missing: git remote add " + target.Remote + " <your-repo-url>\n" +
+			"3. Update remote URL: git remote set-url " + target.Remote + " <correct-url>\n" +
+			"Manual push: " + manualPush
 	}
 	
 	if strings.Contains(errorMsg, "rejected") || strings.Contains(errorMsg, "non-fast-forward") {
 		return "Push rejected (remote has newer commits). Solutions:\n" +
 			"1. Someone else pushed to the repository\n" +
-			"2. Pull latest changes: git pull origin main\n" +
+			"2. Pull latest changes: git pull " + target.Remote + " " + target.RemoteBranch + "\n" +
 			"3. Then retry finalize and push\n" +
-			"Manual push: git push --force-with-lease origin main"
+			"Manual push: " + manualPush
 	}
 	
 	if strings.Contains(errorMsg, "network") || strings.Contains(errorMsg, "timeout") {
@@ -293,13 +510,13 @@ func diagnosePushError(output string, err error) string {
 			"1. Check your internet connection\n" +
 			"2. Try again in a moment\n" +
 			"3. Check if GitHub/GitLab is accessible\n" +
-			"Manual push: git push --force-with-lease origin main"
+			"Manual push: " + manualPush
 	}
 	
 	return "Unknown push error. Solutions:\n" +
 		"1. Check repository access and credentials\n" +
 		"2. Verify remote repository exists\n" +
-		"3. Try manual push: git push --force-with-lease origin main\n" +
+		"3. Try manual push: " + manualPush + "\n" +
 		"4. Check git status and git remote -v"
 }
 
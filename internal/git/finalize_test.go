@@ -0,0 +1,106 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// chdirTemp runs the rest of the test in an empty directory, so stray files are caught
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+	return dir
+}
+
+// scriptFinalize scripts a branch main with two checkpoints on top of a base commit
+func scriptFinalize(fake *FakeRunner, gitDir string) {
+	const (
+		base   = "1111111111111111111111111111111111111111"
+		first  = "2222222222222222222222222222222222222222"
+		second = "3333333333333333333333333333333333333333"
+	)
+	fake.Respond(Result{Stdout: gitDir}, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	fake.Respond(Result{Stdout: "3333333"}, "rev-parse", "--short", "HEAD")
+	fake.Respond(Result{Stdout: "main"}, "rev-parse", "--abbrev-ref", "HEAD")
+	fake.Respond(Result{Stdout: "origin"}, "config", "branch.main.remote")
+	fake.Respond(Result{Stdout: "refs/heads/main"}, "config", "branch.main.merge")
+	fake.Respond(Result{Stdout: "a.go\n"}, "diff", "--name-only")
+	fake.Respond(Result{Stdout: "" +
+		"refs/vibe-check/main/1700000002\x1f3333333\x1f" + second + "\x1f1700000002\x1fCHECKPOINT: second\x1e" +
+		"refs/vibe-check/main/1700000001\x1f2222222\x1f" + first + "\x1f1700000001\x1fCHECKPOINT: first\x1e",
+	}, "for-each-ref", "--sort=-refname")
+	fake.Respond(Result{Stdout: "" +
+		"3333333\x1f" + second + "\x1f1700000002\x1fCHECKPOINT: second\x1e" +
+		"2222222\x1f" + first + "\x1f1700000001\x1fCHECKPOINT: first\x1e" +
+		"1111111\x1f" + base + "\x1f1700000000\x1finit\x1e",
+	}, "log")
+}
+
+func TestFinalizeAndPushRollsBackRejectedPush(t *testing.T) {
+	chdirTemp(t)
+	fake := NewFakeRunner()
+	scriptFinalize(fake, t.TempDir())
+	fake.Fail(1, "! [remote rejected] main -> main (pre-receive hook declined)", "push")
+	repo := &Repo{Runner: fake}
+
+	_, err := repo.FinalizeAndPushWithMessage("Ship it")
+
+	var ferr *FinalizeError
+	if !errors.As(err, &ferr) {
+		t.Fatalf("expected a *FinalizeError, got %v", err)
+	}
+	if ferr.Step != "pushing" {
+		t.Errorf("failed step = %q, want pushing", ferr.Step)
+	}
+	if ferr.Backup == "" {
+		t.Error("no backup branch reported")
+	}
+	if !fake.Ran("push", "--force-with-lease", "origin", "main") {
+		t.Errorf("push was not attempted:\n%v", fake.Commands())
+	}
+	if !fake.Ran("commit", "-m", "Ship it") {
+		t.Errorf("squashed commit was not created:\n%v", fake.Commands())
+	}
+	if fake.Ran("branch", "-D") {
+		t.Error("backup branch deleted although the push failed")
+	}
+}
+
+func TestFinalizeWithEmptyGitDirWritesNothing(t *testing.T) {
+	dir := chdirTemp(t)
+	fake := NewFakeRunner() // every command succeeds with empty output
+	repo := &Repo{Runner: fake}
+
+	if _, err := repo.FinalizeAndPushWithMessage("Ship it"); err == nil {
+		t.Fatal("finalize succeeded without checkpoints")
+	}
+	if fake.Ran("push") {
+		t.Error("pushed without checkpoints")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "vibe-check")); !os.IsNotExist(err) {
+		t.Errorf("state written into the working directory: %v", err)
+	}
+}
+
+func TestFinalizeOutsideRepo(t *testing.T) {
+	fake := NewFakeRunner()
+	fake.Fail(128, "fatal: not a git repository", "rev-parse", "--git-dir")
+	repo := &Repo{Runner: fake}
+
+	if _, err := repo.FinalizeAndPushWithMessage(""); !errors.Is(err, ErrNotRepo) {
+		t.Fatalf("expected ErrNotRepo, got %v", err)
+	}
+	if len(fake.Calls()) != 1 {
+		t.Errorf("ran more than the repository check:\n%v", fake.Commands())
+	}
+}
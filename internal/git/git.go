@@ -1,47 +1,56 @@
 package git
 
 import (
-	"os/exec"
+	"errors"
+	"path/filepath"
 	"strings"
 )
 
+// ErrNotRepo is returned when vibe-check runs outside a Git repository
+var ErrNotRepo = errors.New("not in a Git repository")
+
 // IsRepo checks if the current directory is a Git repository
-func IsRepo() bool {
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	err := cmd.Run()
+func (r *Repo) IsRepo() bool {
+	_, err := r.RunCommand("rev-parse", "--git-dir")
 	return err == nil
 }
 
 // RunCommand executes a git command and returns the output
-func RunCommand(args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	output, err := cmd.CombinedOutput()
-	return strings.TrimSpace(string(output)), err
+func (r *Repo) RunCommand(args ...string) (string, error) {
+	return r.RunCommandContext(r.commandContext(), args...)
+}
+
+// runCommandWithEnv executes a git command with extra environment variables (e.g. GIT_INDEX_FILE)
+func (r *Repo) runCommandWithEnv(env []string, args ...string) (string, error) {
+	return r.run(r.commandContext(), Invocation{Args: args, Env: env})
 }
 
 // GetCurrentCommit returns the current commit hash (short format)
-func GetCurrentCommit() (string, error) {
-	return RunCommand("rev-parse", "--short", "HEAD")
+func (r *Repo) GetCurrentCommit() (string, error) {
+	return r.RunCommand("rev-parse", "--short", "HEAD")
 }
 
-// GetTimestamp returns current timestamp in desired format
-func GetTimestamp() string {
-	cmd := exec.Command("date", "+%d/%m/%Y %H:%M")
-	output, err := cmd.Output()
+// GetStateDir returns the directory where vibe-check keeps its own files (.git/vibe-check)
+func (r *Repo) GetStateDir() (string, error) {
+	gitDir, err := r.RunCommand("rev-parse", "--path-format=absolute", "--git-common-dir")
 	if err != nil {
-		return "unknown"
+		return "", err
+	}
+	// Joining an empty dir would write vibe-check/ into the process directory
+	if gitDir == "" {
+		return "", errors.New("git did not report the repository's git directory")
 	}
-	return strings.TrimSpace(string(output))
+	return filepath.Join(gitDir, "vibe-check"), nil
 }
 
 // GetCurrentBranch returns the current branch name
-func GetCurrentBranch() (string, error) {
-	return RunCommand("rev-parse", "--abbrev-ref", "HEAD")
+func (r *Repo) GetCurrentBranch() (string, error) {
+	return r.RunCommand("rev-parse", "--abbrev-ref", "HEAD")
 }
 
 // HasUncommittedChanges returns true if there are uncommitted changes
-func HasUncommittedChanges() bool {
-	status, err := RunCommand("status", "--porcelain")
+func (r *Repo) HasUncommittedChanges() bool {
+	status, err := r.RunCommand("status", "--porcelain")
 	if err != nil {
 		return false
 	}
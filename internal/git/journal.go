@@ -0,0 +1,523 @@
+package git
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"vibe-check/internal/models"
+)
+
+// The operation journal is an append-only JSON-lines file in the state dir.
+// Every mutating operation records HEAD, the index, the tracked files and the
+// refs it touched before and after it ran, which is enough to revert it
+// (undo) or replay it (redo) without relying on the reflog.
+
+// journalFile is the journal's file name inside the state dir
+const journalFile = "journal"
+
+// Operation kinds recorded in the journal
+const (
+	OpCreate   = "create"
+	OpSwitch   = "switch"
+	OpReturn   = "return"
+	OpFinalize = "finalize"
+	OpRestore  = "restore"
+	OpPrune    = "prune"
+	OpRecover  = "recover"
+	OpUndo     = "undo"
+	OpRedo     = "redo"
+)
+
+// ErrNothingToUndo is returned by Undo when no operation can be reverted
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// ErrNothingToRedo is returned by Redo when no undone operation can be replayed
+var ErrNothingToRedo = errors.New("nothing to redo")
+
+// operation tracks a mutating action while it runs
+type operation struct {
+	repo   *Repo
+	kind   string
+	target string
+	before models.RepoState
+	refs   map[string]string
+	// untracked holds the untracked files before the operation
+	untracked map[string]bool
+}
+
+// beginOperation snapshots the repository before a mutating action. It
+// returns nil during dry runs or when the snapshot fails; the journal is best
+// effort and never makes an operation fail.
+func (r *Repo) beginOperation(kind string) *operation {
+	if r.IsDryRun() {
+		return nil
+	}
+	// Migrate first, or a later migration could bring back checkpoints an undo removed
+	r.migrateReflogCheckpoints()
+
+	state, refs, err := r.snapshotRepo()
+	if err != nil {
+		return nil
+	}
+	untracked, err := r.untrackedFiles()
+	if err != nil {
+		return nil
+	}
+	return &operation{repo: r, kind: kind, before: state, refs: refs, untracked: untracked}
+}
+
+// finish snapshots the repository again and appends the operation to the journal
+func (op *operation) finish(summary string) {
+	if op == nil {
+		return
+	}
+	after, refs, err := op.repo.snapshotRepo()
+	if err != nil {
+		return
+	}
+	kept, err := op.repo.keptFiles(op.before.Worktree, after.Worktree, op.untracked)
+	if err != nil {
+		return
+	}
+
+	entry := models.Operation{
+		ID:      newCheckpointID(),
+		Kind:    op.kind,
+		Time:    time.Now(),
+		Summary: summary,
+		Before:  op.before,
+		After:   after,
+		Refs:    diffRefs(op.refs, refs),
+		Target:  op.target,
+		Kept:    kept,
+	}
+
+	// Operations that changed nothing cannot be undone; undo and redo are
+	// always recorded so the journal replays correctly
+	if entry.Kind != OpUndo && entry.Kind != OpRedo && len(entry.Refs) == 0 && entry.Before == entry.After {
+		return
+	}
+	op.repo.appendJournal(entry)
+}
+
+// snapshotRepo captures HEAD, the index, the tracked files and all branch and checkpoint refs
+func (r *Repo) snapshotRepo() (models.RepoState, map[string]string, error) {
+	var state models.RepoState
+
+	// Unborn branches and detached HEADs are expected; they leave the fields empty
+	state.Head, _ = r.RunCommand("rev-parse", "--verify", "-q", "HEAD")
+	state.Branch, _ = r.RunCommand("symbolic-ref", "-q", "--short", "HEAD")
+	if saved, err := r.readState(); err == nil {
+		state.Origin = saved[stateOriginBranch]
+	}
+
+	var err error
+	if state.Index, err = r.RunCommand("write-tree"); err != nil {
+		// An index with unresolved conflicts has no tree; leave it unrecorded
+		if unmerged, _ := r.RunCommand("ls-files", "-u"); unmerged == "" {
+			return state, nil, fmt.Errorf("failed to snapshot index: %v", err)
+		}
+		state.Index = ""
+	}
+	if state.Worktree, err = r.snapshotTrackedTree(state.Index); err != nil {
+		return state, nil, err
+	}
+
+	output, err := r.RunCommand("for-each-ref", "--format=%(refname) %(objectname)", "refs/heads/", CheckpointRefPrefix, FinalizedRefPrefix)
+	if err != nil {
+		return state, nil, fmt.Errorf("failed to list refs: %v", err)
+	}
+	refs := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if ref, hash, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			refs[ref] = hash
+		}
+	}
+	return state, refs, nil
+}
+
+// snapshotTrackedTree writes a tree of the tracked files as they are in the
+// working tree. Untracked files are left out: undo never touches them, and
+// hashing them on every operation would copy every stray binary or secret
+// into the object store.
+func (r *Repo) snapshotTrackedTree(index string) (string, error) {
+	// Nothing differs from the staging area, so its tree describes the files
+	if index != "" {
+		if _, err := r.RunCommand("diff-files", "--quiet"); err == nil {
+			return index, nil
+		}
+	}
+
+	tmpIndex, err := r.newTempIndex()
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpIndex)
+
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := r.runCommandWithEnv(env, "add", "-u"); err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %v", err)
+	}
+	tree, err := r.runCommandWithEnv(env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %v", err)
+	}
+	return tree, nil
+}
+
+// untrackedFiles lists the untracked files that are not ignored. Only their
+// names are read; nothing is hashed.
+func (r *Repo) untrackedFiles() (map[string]bool, error) {
+	output, err := r.RunCommand("ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list untracked files: %v", err)
+	}
+	files := make(map[string]bool)
+	for _, name := range splitNul(output) {
+		files[name] = true
+	}
+	return files, nil
+}
+
+// keptFiles lists the files an operation started tracking while they were
+// untracked, like a checkpoint of new files, or stopped tracking while they
+// stayed on disk. Undo and redo must not delete or overwrite them.
+func (r *Repo) keptFiles(before, after string, untrackedBefore map[string]bool) ([]string, error) {
+	if before == after {
+		return nil, nil
+	}
+	untrackedAfter, err := r.untrackedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []string
+	for _, side := range []struct {
+		filter    string
+		untracked map[string]bool
+	}{{"--diff-filter=A", untrackedBefore}, {"--diff-filter=D", untrackedAfter}} {
+		if len(side.untracked) == 0 {
+			continue
+		}
+		output, err := r.RunCommand("diff", "--name-only", "--no-renames", "-z", side.filter, before, after)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compare snapshots: %v", err)
+		}
+		for _, name := range splitNul(output) {
+			if side.untracked[name] {
+				kept = append(kept, name)
+			}
+		}
+	}
+	return kept, nil
+}
+
+// treeWithout returns a tree without the given paths
+func (r *Repo) treeWithout(tree string, paths []string) (string, error) {
+	tmpIndex, err := r.newTempIndex()
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpIndex)
+
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := r.runCommandWithEnv(env, "read-tree", tree); err != nil {
+		return "", err
+	}
+	stdin := strings.Join(paths, "\x00") + "\x00"
+	if _, err := r.Exec(r.commandContext(), Invocation{
+		Args:  []string{"update-index", "--force-remove", "-z", "--stdin"},
+		Env:   env,
+		Stdin: strings.NewReader(stdin),
+	}); err != nil {
+		return "", err
+	}
+	return r.runCommandWithEnv(env, "write-tree")
+}
+
+// diffRefs lists the refs that differ between two snapshots
+func diffRefs(before, after map[string]string) []models.RefChange {
+	var changes []models.RefChange
+	for ref, old := range before {
+		if after[ref] != old {
+			changes = append(changes, models.RefChange{Ref: ref, Old: old, New: after[ref]})
+		}
+	}
+	for ref, hash := range after {
+		if _, ok := before[ref]; !ok {
+			changes = append(changes, models.RefChange{Ref: ref, New: hash})
+		}
+	}
+	return changes
+}
+
+// journalPath returns the absolute path of the journal file
+func (r *Repo) journalPath() (string, error) {
+	stateDir, err := r.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, journalFile), nil
+}
+
+// appendJournal writes one entry to the end of the journal
+func (r *Repo) appendJournal(entry models.Operation) error {
+	path, err := r.journalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// readJournal returns every journal entry, oldest first
+func (r *Repo) readJournal() ([]models.Operation, error) {
+	path, err := r.journalPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []models.Operation
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry models.Operation
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			// Skip a torn last line rather than losing the whole history
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// replayJournal works out which operations are in effect (done, oldest first)
+// and which were undone and can be redone (undone, most recent last)
+func replayJournal(entries []models.Operation) (done, undone []models.Operation) {
+	for _, entry := range entries {
+		switch entry.Kind {
+		case OpUndo:
+			if n := len(done); n > 0 && done[n-1].ID == entry.Target {
+				undone = append(undone, done[n-1])
+				done = done[:n-1]
+			}
+		case OpRedo:
+			if n := len(undone); n > 0 && undone[n-1].ID == entry.Target {
+				done = append(done, undone[n-1])
+				undone = undone[:n-1]
+			}
+		default:
+			done = append(done, entry)
+			// A new operation discards whatever could have been redone
+			undone = nil
+		}
+	}
+	return done, undone
+}
+
+// GetHistory returns the operations recorded in the journal, newest first.
+// Operations that are currently undone are flagged.
+func (r *Repo) GetHistory() ([]models.Operation, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+	entries, err := r.readJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+
+	_, undone := replayJournal(entries)
+	isUndone := make(map[string]bool)
+	for _, op := range undone {
+		isUndone[op.ID] = true
+	}
+
+	history := make([]models.Operation, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		entry.Undone = isUndone[entry.ID]
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// Undo reverts the most recent operation that is still in effect and returns it
+func (r *Repo) Undo() (*models.Operation, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+	entries, err := r.readJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+	done, _ := replayJournal(entries)
+	if len(done) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	op := done[len(done)-1]
+
+	record := r.beginOperation(OpUndo)
+	if err := r.restoreRepoState(op.After, op.Before, reverseRefChanges(op.Refs), op.Kept); err != nil {
+		return nil, fmt.Errorf("cannot undo %s: %v", op.Summary, err)
+	}
+	if record != nil {
+		record.target = op.ID
+		record.finish("undo " + op.Summary)
+	}
+	return &op, nil
+}
+
+// Redo replays the most recently undone operation and returns it
+func (r *Repo) Redo() (*models.Operation, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+	entries, err := r.readJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %v", err)
+	}
+	_, undone := replayJournal(entries)
+	if len(undone) == 0 {
+		return nil, ErrNothingToRedo
+	}
+	op := undone[len(undone)-1]
+
+	record := r.beginOperation(OpRedo)
+	if err := r.restoreRepoState(op.Before, op.After, op.Refs, op.Kept); err != nil {
+		return nil, fmt.Errorf("cannot redo %s: %v", op.Summary, err)
+	}
+	if record != nil {
+		record.target = op.ID
+		record.finish("redo " + op.Summary)
+	}
+	return &op, nil
+}
+
+// reverseRefChanges swaps the old and new value of every ref change
+func reverseRefChanges(changes []models.RefChange) []models.RefChange {
+	reversed := make([]models.RefChange, len(changes))
+	for i, change := range changes {
+		reversed[i] = models.RefChange{Ref: change.Ref, Old: change.New, New: change.Old}
+	}
+	return reversed
+}
+
+// restoreRepoState moves the repository from one recorded state to another.
+// It refuses unless the repository is still exactly in the from state, so
+// nothing made since the operation can be lost. The kept files are left as
+// they are on disk.
+func (r *Repo) restoreRepoState(from, to models.RepoState, refs []models.RefChange, kept []string) error {
+	current, _, err := r.snapshotRepo()
+	if err != nil {
+		return fmt.Errorf("cannot read repository state: %v", err)
+	}
+	if drift := describeDrift(current, from); drift != "" {
+		return fmt.Errorf("the repository changed since then (%s). "+
+			"Only the latest operation can be reverted, and only while nothing else has changed", drift)
+	}
+	if to.Index == "" {
+		return errors.New("the staging area had unresolved conflicts then, which cannot be recreated")
+	}
+
+	// Refs first, in one transaction that also checks nobody moved them since
+	if len(refs) > 0 {
+		var stdin strings.Builder
+		stdin.WriteString("start\n")
+		for _, change := range refs {
+			switch {
+			case change.New == "":
+				stdin.WriteString(fmt.Sprintf("delete %s %s\n", change.Ref, change.Old))
+			case change.Old == "":
+				stdin.WriteString(fmt.Sprintf("create %s %s\n", change.Ref, change.New))
+			default:
+				stdin.WriteString(fmt.Sprintf("update %s %s %s\n", change.Ref, change.New, change.Old))
+			}
+		}
+		stdin.WriteString("prepare\ncommit\n")
+
+		result, err := r.Exec(r.commandContext(), Invocation{Args: []string{"update-ref", "--stdin"}, Stdin: strings.NewReader(stdin.String())})
+		if err != nil {
+			return fmt.Errorf("refs were changed outside vibe-check: %v", strings.TrimSpace(result.Stderr))
+		}
+	}
+
+	// Then HEAD, which may be attached to a branch or detached
+	if to.Branch != "" {
+		if _, err := r.RunCommand("symbolic-ref", "HEAD", "refs/heads/"+to.Branch); err != nil {
+			return fmt.Errorf("failed to check out %s: %v", to.Branch, err)
+		}
+	} else if to.Head != "" {
+		if _, err := r.RunCommand("update-ref", "--no-deref", "HEAD", to.Head); err != nil {
+			return fmt.Errorf("failed to move HEAD: %v", err)
+		}
+	}
+
+	// Finally the working tree and the staging area
+	if from.Worktree != to.Worktree {
+		fromTree, toTree := from.Worktree, to.Worktree
+		if len(kept) > 0 {
+			if fromTree, err = r.treeWithout(fromTree, kept); err == nil {
+				toTree, err = r.treeWithout(toTree, kept)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to prepare working tree: %v", err)
+			}
+		}
+		if _, err := r.RunCommand("read-tree", fromTree); err != nil {
+			return fmt.Errorf("failed to prepare index: %v", err)
+		}
+		r.RunCommand("update-index", "-q", "--refresh")
+		if _, err := r.RunCommand("read-tree", "-m", "-u", fromTree, toTree); err != nil {
+			return fmt.Errorf("failed to restore working tree: %v", err)
+		}
+	}
+	if _, err := r.RunCommand("read-tree", to.Index); err != nil {
+		return fmt.Errorf("failed to restore index: %v", err)
+	}
+
+	return r.updateState(map[string]string{stateOriginBranch: to.Origin})
+}
+
+// describeDrift names what differs between the current and the expected state
+func describeDrift(current, expected models.RepoState) string {
+	var drift []string
+	if current.Branch != expected.Branch || current.Head != expected.Head {
+		drift = append(drift, "HEAD moved")
+	}
+	if current.Worktree != expected.Worktree {
+		drift = append(drift, "files changed")
+	} else if current.Index != expected.Index {
+		drift = append(drift, "staged changes differ")
+	}
+	return strings.Join(drift, ", ")
+}
@@ -0,0 +1,167 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// checkpointRefs lists the checkpoint refs with the commits they point at
+func (r *testRepo) checkpointRefs() string {
+	r.t.Helper()
+	return r.git(r.dir, "for-each-ref", "--format=%(refname) %(objectname)", CheckpointRefPrefix)
+}
+
+func TestUndoAndRedoCheckpoint(t *testing.T) {
+	r := newTestRepo(t)
+	head, refs := r.git(r.dir, "rev-parse", "HEAD"), r.checkpointRefs()
+	r.checkpoint("one\n")
+	checkpoint, checkpointed := r.git(r.dir, "rev-parse", "HEAD"), r.checkpointRefs()
+
+	if _, err := r.Undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if got := r.git(r.dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD at %s after undo, want %s", got, head)
+	}
+	if got := r.checkpointRefs(); got != refs {
+		t.Errorf("checkpoint refs after undo:\n%s\nwant:\n%s", got, refs)
+	}
+	if got := r.git(r.dir, "status", "--porcelain"); got != "?? app.txt" {
+		t.Errorf("status after undo = %q, want app.txt back as an untracked file", got)
+	}
+	if content, err := os.ReadFile(filepath.Join(r.dir, "app.txt")); err != nil || string(content) != "one\n" {
+		t.Errorf("app.txt after undo = %q, %v", content, err)
+	}
+
+	if _, err := r.Redo(); err != nil {
+		t.Fatalf("redo failed: %v", err)
+	}
+	if got := r.git(r.dir, "rev-parse", "HEAD"); got != checkpoint {
+		t.Errorf("HEAD at %s after redo, want %s", got, checkpoint)
+	}
+	if got := r.checkpointRefs(); got != checkpointed {
+		t.Errorf("checkpoint refs after redo:\n%s\nwant:\n%s", got, checkpointed)
+	}
+	if got := r.git(r.dir, "status", "--porcelain"); got != "" {
+		t.Errorf("status after redo = %q, want clean", got)
+	}
+}
+
+func TestUndoAndRedoFinalize(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("one\n")
+	r.checkpoint("two\n")
+	head, refs := r.git(r.dir, "rev-parse", "HEAD"), r.checkpointRefs()
+	if _, err := r.FinalizeAndPushWithMessage("Ship it"); err != nil {
+		t.Fatalf("finalize failed: %v", err)
+	}
+	finalized, finalizedRefs := r.git(r.dir, "rev-parse", "HEAD"), r.checkpointRefs()
+
+	op, err := r.Undo()
+	if err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	if op.Kind != OpFinalize {
+		t.Errorf("undid a %s, want the finalize", op.Kind)
+	}
+	if got := r.git(r.dir, "rev-parse", "main"); got != head {
+		t.Errorf("main at %s after undo, want the last checkpoint %s", got, head)
+	}
+	if got := r.checkpointRefs(); got != refs {
+		t.Errorf("checkpoint refs after undo:\n%s\nwant:\n%s", got, refs)
+	}
+	if got := r.git(r.dir, "status", "--porcelain"); got != "" {
+		t.Errorf("status after undo = %q, want clean", got)
+	}
+
+	if _, err := r.Redo(); err != nil {
+		t.Fatalf("redo failed: %v", err)
+	}
+	if got := r.git(r.dir, "rev-parse", "main"); got != finalized {
+		t.Errorf("main at %s after redo, want the finalized commit %s", got, finalized)
+	}
+	if got := r.checkpointRefs(); got != finalizedRefs {
+		t.Errorf("checkpoint refs after redo:\n%s\nwant:\n%s", got, finalizedRefs)
+	}
+}
+
+func TestUndoRefusesAfterTrackedFilesChanged(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("one\n")
+	head := r.git(r.dir, "rev-parse", "HEAD")
+	r.write("app.txt", "later\n")
+
+	if _, err := r.Undo(); err == nil || !strings.Contains(err.Error(), "files changed") {
+		t.Fatalf("expected undo to refuse, got %v", err)
+	}
+	if got := r.git(r.dir, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD moved to %s", got)
+	}
+}
+
+func TestSnapshotLeavesUntrackedFilesOutOfTheObjectStore(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("README.md", "edited\n")
+	r.write(".env", "SECRET=do-not-store\n")
+	secret := r.git(r.dir, "hash-object", ".env")
+
+	state, _, err := r.snapshotRepo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Worktree == state.Index {
+		t.Error("worktree tree does not include the edit to README.md")
+	}
+	if _, err := r.RunCommand("cat-file", "-e", secret); err == nil {
+		t.Error("the untracked .env was written to the object store")
+	}
+}
+
+func TestSnapshotWithUnresolvedConflicts(t *testing.T) {
+	r := newTestRepo(t)
+	r.git(r.dir, "checkout", "-q", "-b", "other")
+	r.write("app.txt", "theirs\n")
+	r.git(r.dir, "add", "app.txt")
+	r.git(r.dir, "commit", "-q", "-m", "theirs")
+	r.git(r.dir, "checkout", "-q", "main")
+	r.write("app.txt", "ours\n")
+	r.git(r.dir, "add", "app.txt")
+	r.git(r.dir, "commit", "-q", "-m", "ours")
+	r.RunCommand("merge", "-q", "other")
+	if r.git(r.dir, "ls-files", "-u") == "" {
+		t.Fatal("merge did not leave a conflict")
+	}
+
+	state, _, err := r.snapshotRepo()
+	if err != nil {
+		t.Fatalf("snapshot failed with a conflicted index: %v", err)
+	}
+	if state.Index != "" || state.Worktree == "" {
+		t.Errorf("state = %+v, want no index tree but a worktree tree", state)
+	}
+	if _, err := os.Stat(filepath.Join(r.dir, "app.txt")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUndoSwitchRestoresFilesAndKeepsUntrackedOnes(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("one\n")
+	first := r.git(r.dir, "rev-parse", "--short", "HEAD")
+	r.checkpoint("two\n")
+	r.write("notes.txt", "scratch\n")
+	if err := r.SwitchToCheckpoint(first); err != nil {
+		t.Fatalf("switch failed: %v", err)
+	}
+
+	if _, err := r.Undo(); err != nil {
+		t.Fatalf("undo failed: %v", err)
+	}
+	for name, want := range map[string]string{"app.txt": "two\n", "notes.txt": "scratch\n"} {
+		if content, err := os.ReadFile(filepath.Join(r.dir, name)); err != nil || string(content) != want {
+			t.Errorf("%s after undo = %q, %v; want %q", name, content, err, want)
+		}
+	}
+}
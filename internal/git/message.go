@@ -0,0 +1,148 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	_ "time/tzdata" // timezones on systems without a zoneinfo database, such as Windows
+	"vibe-check/internal/config"
+)
+
+// Commit messages are rendered from Go text/template templates set by the
+// messages.* settings, e.g.
+//
+//	vibe-check config set messages.checkpoint_template '{{.Branch}}: {{or .Note "wip"}}'
+//	vibe-check config set messages.finalize_template '{{.Branch}}: {{.Count}} checkpoint(s), {{.DiffStat}}'
+//	vibe-check config set messages.time_format '2006-01-02 15:04'
+//	vibe-check config set messages.timezone UTC
+const (
+	configCheckpointTemplate     = "messages.checkpoint_template"
+	configCheckpointBodyTemplate = "messages.checkpoint_body_template"
+	configFinalizeTemplate       = "messages.finalize_template"
+	configTimeFormat             = "messages.time_format"
+	configTimezone               = "messages.timezone"
+)
+
+// MessageData is what message templates can refer to. Files, DiffStat and
+// Count are methods so they are only worked out when a template uses them.
+type MessageData struct {
+	Timestamp string    // Time in the configured format and timezone
+	Time      time.Time // in the configured timezone
+	Note      string    // checkpoint note; empty for finalize
+	Branch    string
+
+	files    func() ([]string, error)
+	diffStat func() (string, error)
+	count    func() (int, error)
+}
+
+// Files lists the files the commit changes
+func (d MessageData) Files() ([]string, error) {
+	if d.files == nil {
+		return nil, nil
+	}
+	return d.files()
+}
+
+// DiffStat summarises the commit, e.g. "2 files changed, 5 insertions(+)"
+func (d MessageData) DiffStat() (string, error) {
+	if d.diffStat == nil {
+		return "", nil
+	}
+	return d.diffStat()
+}
+
+// Count is the number of checkpoints on the branch, or squashed by finalize
+func (d MessageData) Count() (int, error) {
+	if d.count == nil {
+		return 0, nil
+	}
+	return d.count()
+}
+
+// templateFuncs are available to message templates besides the text/template builtins
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// GetTimestamp returns the current time in the configured format and timezone
+func GetTimestamp() string {
+	now, err := messageTime()
+	if err != nil {
+		return "unknown"
+	}
+	return now.Format(timeFormat())
+}
+
+// newMessageData returns template data stamped with the current time
+func newMessageData(branch, note string) (MessageData, error) {
+	now, err := messageTime()
+	if err != nil {
+		return MessageData{}, err
+	}
+	return MessageData{Timestamp: now.Format(timeFormat()), Time: now, Note: note, Branch: branch}, nil
+}
+
+// messageTime returns the current time in the configured timezone
+func messageTime() (time.Time, error) {
+	name := config.String(configTimezone)
+	if name == "" {
+		return time.Now(), nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: %v", configTimezone, name, err)
+	}
+	return time.Now().In(location), nil
+}
+
+// timeFormat returns the configured Go time layout for timestamps
+func timeFormat() string {
+	return config.String(configTimeFormat)
+}
+
+// parseMessageTemplate reads the template set under key
+func parseMessageTemplate(key string) (*template.Template, error) {
+	tmpl, err := template.New(key).Funcs(templateFuncs).Parse(config.String(key))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", key, err)
+	}
+	return tmpl, nil
+}
+
+// renderMessage renders the template configured under key with data
+func renderMessage(key string, data MessageData) (string, error) {
+	tmpl, err := parseMessageTemplate(key)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %v", key, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// checkMessageTemplates validates the finalize template and timezone before
+// anything is changed, so a bad setting cannot fail finalize halfway
+func checkMessageTemplates() error {
+	if _, err := parseMessageTemplate(configFinalizeTemplate); err != nil {
+		return err
+	}
+	_, err := messageTime()
+	return err
+}
+
+// splitLines splits command output into non-empty lines
+func splitLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,137 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Partial checkpoints are built in a temporary index that starts from HEAD,
+// so only the selected files or hunks are committed. The working tree is never
+// touched; afterwards the real index entries of the committed files are reset
+// to the new commit, leaving everything else staged or unstaged as it was.
+
+// createPartialCheckpoint commits the selected changes on top of HEAD and
+// returns the new commit and its subject
+func (r *Repo) createPartialCheckpoint(opts CheckpointOptions, branch, id string) (string, string, error) {
+	paths := []string{r.scopePathspec()}
+	if len(opts.Paths) > 0 {
+		paths = nil
+		for _, p := range opts.Paths {
+			paths = append(paths, r.userPathspec(p))
+		}
+	}
+
+	tmpIndex, err := r.newTempIndex()
+	if err != nil {
+		return "", "", err
+	}
+	defer os.Remove(tmpIndex)
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+
+	// Unborn branches start from an empty tree
+	parent, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD")
+	if err != nil {
+		parent = ""
+	}
+	readTree := []string{"read-tree", "--empty"}
+	if parent != "" {
+		readTree = []string{"read-tree", parent}
+	}
+	if _, err := r.runCommandWithEnv(env, readTree...); err != nil {
+		return "", "", fmt.Errorf("failed to prepare temporary index: %v", err)
+	}
+	// The tree the selection is compared with; empty on an unborn branch
+	base, err := r.runCommandWithEnv(env, "write-tree")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to prepare temporary index: %v", err)
+	}
+
+	if opts.Interactive {
+		// `add --patch` only offers tracked files; mark new ones so they are offered too
+		if _, err := r.runCommandWithEnv(env, append([]string{"add", "--intent-to-add", "--"}, paths...)...); err != nil {
+			return "", "", fmt.Errorf("failed to select changes: %v", err)
+		}
+		inv := Invocation{Args: append([]string{"add", "--patch", "--"}, paths...), Env: env, Interactive: true}
+		if _, err := r.Exec(r.commandContext(), inv); err != nil {
+			return "", "", fmt.Errorf("failed to select hunks: %v", err)
+		}
+	} else {
+		if _, err := r.runCommandWithEnv(env, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+			return "", "", fmt.Errorf("failed to select changes: %v", err)
+		}
+	}
+
+	tree, err := r.runCommandWithEnv(env, "write-tree")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to write checkpoint tree: %v", err)
+	}
+	if tree == base && !r.IsDryRun() {
+		return "", "", ErrNoChanges
+	}
+
+	// The message describes what was selected, not every uncommitted change
+	data, err := r.checkpointMessageData(branch, opts.Note)
+	if err != nil {
+		return "", "", err
+	}
+	data.files = func() ([]string, error) {
+		output, err := r.RunCommand("diff", "--name-only", base, tree)
+		return splitLines(output), err
+	}
+	data.diffStat = func() (string, error) {
+		return r.RunCommand("diff", "--shortstat", base, tree)
+	}
+	message, body, err := renderCheckpointMessage(data)
+	if err != nil {
+		return "", "", err
+	}
+
+	args := []string{"commit-tree", tree}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	args = append(args, checkpointMessageArgs(id, message, body, opts.Note)...)
+	commit, err := r.RunCommand(args...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create checkpoint: %v", err)
+	}
+
+	// Move the branch (or a detached HEAD), refusing if it moved meanwhile
+	update := []string{"update-ref", "-m", "vibe-check: partial checkpoint", "HEAD", commit}
+	if parent != "" {
+		update = append(update, parent)
+	}
+	if _, err := r.RunCommand(update...); err != nil {
+		return "", "", fmt.Errorf("failed to move HEAD to the checkpoint: %v", err)
+	}
+
+	// The committed files now match HEAD in the real index too. Only they are
+	// reset: a pathspec such as ":/" would also unstage files the user declined.
+	if err := r.resetCommittedPaths(base, tree); err != nil {
+		return "", "", fmt.Errorf("failed to update the index: %v", err)
+	}
+	return commit, message, nil
+}
+
+// resetCommittedPaths resets the real index entries of the files that differ
+// between two trees. The paths are passed on stdin, however many there are.
+func (r *Repo) resetCommittedPaths(base, tree string) error {
+	changed, err := r.RunCommand("diff", "--name-only", "--no-renames", "-z", base, tree)
+	if err != nil {
+		return err
+	}
+	var pathspecs strings.Builder
+	for _, name := range splitNul(changed) {
+		pathspecs.WriteString(":(top,literal)" + name + "\x00")
+	}
+	if pathspecs.Len() == 0 {
+		return nil
+	}
+	inv := Invocation{
+		Args:  []string{"reset", "-q", "--pathspec-from-file=-", "--pathspec-file-nul"},
+		Stdin: strings.NewReader(pathspecs.String()),
+	}
+	_, err = r.Exec(r.commandContext(), inv)
+	return err
+}
@@ -0,0 +1,81 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// answerPrompts feeds input to interactive git commands and hides their prompts
+func answerPrompts(t *testing.T, input string) {
+	t.Helper()
+	answers := filepath.Join(t.TempDir(), "answers")
+	if err := os.WriteFile(answers, []byte(input), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	stdin, err := os.Open(answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, err := os.Create(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = oldStdin, oldStdout
+		stdin.Close()
+		stdout.Close()
+	})
+}
+
+func TestInteractiveCheckpointKeepsDeclinedFilesStaged(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "a\n")
+	r.write("b.txt", "b\n")
+	r.git(r.dir, "add", "-A")
+	r.git(r.dir, "commit", "-q", "-m", "files")
+
+	r.write("a.txt", "a changed\n")
+	r.write("b.txt", "b changed\n")
+	r.git(r.dir, "add", "b.txt")
+	answerPrompts(t, "y\nn\n") // take the hunk in a.txt, decline the one in b.txt
+
+	if _, err := r.CreateCheckpointWithOptions(CheckpointOptions{Interactive: true}); err != nil {
+		t.Fatalf("creating checkpoint: %v", err)
+	}
+	if got := r.git(r.dir, "show", "--format=", "--name-only", "HEAD"); got != "a.txt" {
+		t.Errorf("checkpoint holds %q, want a.txt", got)
+	}
+	if got := r.git(r.dir, "status", "--porcelain", "--", "b.txt"); got != "M  b.txt" {
+		t.Errorf("b.txt status = %q, want it still staged", got)
+	}
+}
+
+func TestChangedFilesWithUnusualNames(t *testing.T) {
+	r := newTestRepo(t)
+	r.git(r.dir, "mv", "README.md", "read me.md")
+	r.write("my file.txt", "spaces\n")
+	r.write("ünïcode.txt", "accents\n")
+
+	files, err := r.ChangedFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"README.md", "read me.md", "my file.txt", "ünïcode.txt"} {
+		if !slices.Contains(files, want) {
+			t.Errorf("r.ChangedFiles() = %q, missing %q", files, want)
+		}
+	}
+
+	// The picker anchors each selected file like this
+	opts := CheckpointOptions{Paths: []string{":(top,literal)my file.txt", ":(top,literal)ünïcode.txt"}}
+	if _, err := r.CreateCheckpointWithOptions(opts); err != nil {
+		t.Fatalf("creating checkpoint: %v", err)
+	}
+	if got := r.git(r.dir, "status", "--porcelain", "-z", "--", "my file.txt", "ünïcode.txt"); got != "" {
+		t.Errorf("selected files not checkpointed, status %q", got)
+	}
+}
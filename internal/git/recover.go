@@ -0,0 +1,118 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// FindLostCheckpoints returns checkpoint commits no branch, tag or checkpoint
+// ref reaches any more, newest first. Commits only the reflog still knows
+// about are included, as are dangling ones left after the reflog expired.
+func (r *Repo) FindLostCheckpoints() ([]models.Checkpoint, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+
+	output, err := r.RunCommand("fsck", "--unreachable", "--no-reflogs", "--no-progress")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan for unreachable commits: %v", err)
+	}
+
+	// Large repositories have more unreachable commits than fit on a command line
+	var commits strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 3 && fields[0] == "unreachable" && fields[1] == "commit" {
+			commits.WriteString(fields[2] + "\n")
+		}
+	}
+
+	checkpoints := []models.Checkpoint{}
+	if commits.Len() == 0 {
+		return checkpoints, nil
+	}
+
+	inv := Invocation{
+		Args:  []string{"log", "--no-walk", "--stdin", "--format=%h" + fieldSep + "%ct" + fieldSep + "%B" + recordSep},
+		Stdin: strings.NewReader(commits.String()),
+	}
+	result, err := r.Exec(r.commandContext(), inv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read unreachable commits: %v", err)
+	}
+
+	for _, fields := range splitRecords(result.Stdout) {
+		if len(fields) < 3 {
+			continue
+		}
+		cp, ok := ParseCheckpoint(fields[0], fields[2])
+		if !ok {
+			continue
+		}
+		cp.Time = parseUnix(fields[1])
+		checkpoints = append(checkpoints, cp)
+	}
+
+	sort.SliceStable(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Time.After(checkpoints[j].Time)
+	})
+	return checkpoints, nil
+}
+
+// RecoverCheckpoint brings back a lost checkpoint. With a branch name it
+// creates that branch at the checkpoint; otherwise the checkpoint is recorded
+// again in the namespace of the current branch.
+func (r *Repo) RecoverCheckpoint(hash, branch string) (*models.Checkpoint, error) {
+	lost, err := r.FindLostCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *models.Checkpoint
+	for i := range lost {
+		if strings.HasPrefix(lost[i].Hash, hash) || strings.HasPrefix(hash, lost[i].Hash) {
+			found = &lost[i]
+			break
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no lost checkpoint %s. Run `vibe-check recover` to list them", hash)
+	}
+
+	commit, err := r.RunCommand("rev-parse", found.Hash+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %v", found.Hash, err)
+	}
+
+	op := r.beginOperation(OpRecover)
+	if branch != "" {
+		if _, err := r.RunCommand("branch", branch, commit); err != nil {
+			return nil, fmt.Errorf("failed to create branch %s: %v", branch, err)
+		}
+		found.Branch = branch
+		op.finish(fmt.Sprintf("recover [%s] to branch %s", found.Hash, branch))
+		return found, nil
+	}
+
+	namespace, err := r.GetCheckpointBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine current branch: %v", err)
+	}
+
+	// Legacy checkpoints carry no id; derive a sortable one from the commit time
+	id := found.ID
+	if id == "" {
+		id = strconv.FormatInt(found.Time.UnixNano(), 10)
+	}
+	if err := r.recordCheckpointRef(namespace, id, commit); err != nil {
+		return nil, err
+	}
+	found.ID = id
+	found.Ref = CheckpointRef(namespace, id)
+	found.Branch = namespace
+	op.finish(fmt.Sprintf("recover [%s] %s", found.Hash, found.Message))
+	return found, nil
+}
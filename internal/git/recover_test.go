@@ -0,0 +1,42 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindLostCheckpointsPassesCommitsOnStdin(t *testing.T) {
+	fake := NewFakeRunner()
+	repo := &Repo{Runner: fake}
+
+	var fsck, want strings.Builder
+	for i := 0; i < 50000; i++ {
+		hash := fmt.Sprintf("%040x", i)
+		fsck.WriteString("unreachable commit " + hash + "\n")
+		want.WriteString(hash + "\n")
+	}
+	fake.Respond(Result{Stdout: fsck.String()}, "fsck")
+	fake.Respond(Result{Stdout: "abc1234" + fieldSep + "1700000000" + fieldSep +
+		"CHECKPOINT: lost\n\nVibe-Checkpoint: 1\n" + recordSep}, "log")
+
+	lost, err := repo.FindLostCheckpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lost) != 1 || lost[0].Hash != "abc1234" {
+		t.Errorf("lost checkpoints = %+v, want [abc1234]", lost)
+	}
+
+	for i, inv := range fake.Calls() {
+		if inv.Args[0] != "log" {
+			continue
+		}
+		if len(inv.Args) != 4 || inv.Args[2] != "--stdin" {
+			t.Errorf("log invoked with %d arguments, want the commits on stdin", len(inv.Args))
+		}
+		if got := fake.Stdin(i); got != want.String() {
+			t.Errorf("log read %d bytes on stdin, want %d", len(got), want.Len())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"vibe-check/internal/models"
+)
+
+// CheckpointRefPrefix is the ref namespace where checkpoints are recorded.
+// Each checkpoint lives at refs/vibe-check/<branch>/<id>.
+const CheckpointRefPrefix = "refs/vibe-check/"
+
+// detachedNamespace is used for checkpoints that cannot be tied to any branch
+const detachedNamespace = "detached"
+
+// migrationMarker is the file (inside the state dir) written once reflog checkpoints are migrated
+const migrationMarker = "reflog-migrated"
+
+// CheckpointRef returns the full ref name for a checkpoint on a branch
+func CheckpointRef(branch, id string) string {
+	return CheckpointRefPrefix + branch + "/" + id
+}
+
+// newCheckpointID returns a new, lexically sortable checkpoint identifier
+func newCheckpointID() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 10)
+}
+
+// GetCheckpointBranch returns the branch namespace checkpoints for the current HEAD belong to
+func (r *Repo) GetCheckpointBranch() (string, error) {
+	branch, err := r.GetCurrentBranch()
+	if err != nil {
+		return "", err
+	}
+	if branch != "HEAD" {
+		return branch, nil
+	}
+
+	// Detached HEAD - use the namespace of the checkpoint we are sitting on
+	output, err := r.RunCommand("for-each-ref", "--points-at", "HEAD", "--format=%(refname)", CheckpointRefPrefix)
+	if err == nil {
+		for _, ref := range strings.Split(output, "\n") {
+			if b, _, ok := parseCheckpointRef(strings.TrimSpace(ref)); ok {
+				return b, nil
+			}
+		}
+	}
+
+	// Then the branch the user switched away from
+	if origin := r.GetOriginBranch(); origin != "" {
+		return origin, nil
+	}
+
+	// Otherwise fall back to a branch that contains HEAD
+	output, err = r.RunCommand("for-each-ref", "--contains", "HEAD", "--format=%(refname:short)", "refs/heads/")
+	if err == nil {
+		for _, b := range strings.Split(output, "\n") {
+			if b = strings.TrimSpace(b); b != "" {
+				return b, nil
+			}
+		}
+	}
+
+	return detachedNamespace, nil
+}
+
+// parseCheckpointRef splits refs/vibe-check/<branch>/<id> into its branch and id
+func parseCheckpointRef(ref string) (branch, id string, ok bool) {
+	if !strings.HasPrefix(ref, CheckpointRefPrefix) {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(ref, CheckpointRefPrefix)
+	i := strings.LastIndex(rest, "/")
+	if i <= 0 || i == len(rest)-1 {
+		return "", "", false
+	}
+	return rest[:i], rest[i+1:], true
+}
+
+// listCheckpointRefs returns the checkpoints recorded for a branch, newest first
+func (r *Repo) listCheckpointRefs(branch string) ([]models.Checkpoint, error) {
+	output, err := r.RunCommand("for-each-ref", "--sort=-refname",
+		"--format=%(refname)%1f%(objectname:short)%1f%(objectname)%1f%(committerdate:unix)%1f%(contents)%1e",
+		CheckpointRefPrefix+branch+"/")
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []models.Checkpoint
+	for _, fields := range splitRecords(output) {
+		if len(fields) < 5 {
+			continue
+		}
+
+		refBranch, id, ok := parseCheckpointRef(strings.TrimSpace(fields[0]))
+		// Skip refs of nested branches (e.g. feature/x when listing feature)
+		if !ok || refBranch != branch {
+			continue
+		}
+
+		// Anything stored in the namespace is a checkpoint, even without trailers
+		cp, _ := ParseCheckpoint(fields[1], fields[4])
+		cp.SHA = fields[2]
+		cp.Time = parseUnix(fields[3])
+		cp.ID = id
+		cp.Ref = strings.TrimSpace(fields[0])
+		cp.Branch = branch
+		checkpoints = append(checkpoints, cp)
+	}
+
+	return checkpoints, nil
+}
+
+// recordCheckpointRef stores a commit under the checkpoint namespace of a branch
+func (r *Repo) recordCheckpointRef(branch, id, commit string) error {
+	_, err := r.RunCommand("update-ref", CheckpointRef(branch, id), commit)
+	if err != nil {
+		return fmt.Errorf("failed to record checkpoint ref: %v", err)
+	}
+	return nil
+}
+
+// deleteCheckpointRefs removes the refs of the given checkpoints
+func (r *Repo) deleteCheckpointRefs(checkpoints []models.Checkpoint) error {
+	for _, cp := range checkpoints {
+		if cp.Ref == "" {
+			continue
+		}
+		if _, err := r.RunCommand("update-ref", "-d", cp.Ref); err != nil {
+			return fmt.Errorf("failed to delete checkpoint ref %s: %v", cp.Ref, err)
+		}
+	}
+	return nil
+}
+
+// migrateReflogCheckpoints copies checkpoints found in the reflog into the ref namespace.
+// It runs once per repository; a marker file in the state dir records completion.
+func (r *Repo) migrateReflogCheckpoints() error {
+	stateDir, err := r.GetStateDir()
+	if err != nil {
+		return err
+	}
+	marker := filepath.Join(stateDir, migrationMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	}
+
+	reflogOutput, err := r.RunCommand("reflog", "--format=%H"+fieldSep+"%ct"+fieldSep+"%B"+recordSep)
+	if err != nil {
+		// Fresh repositories have no reflog yet - nothing to migrate
+		reflogOutput = ""
+	}
+
+	// Commits that already have a checkpoint ref
+	known := make(map[string]bool)
+	existing, _ := r.RunCommand("for-each-ref", "--format=%(objectname)", CheckpointRefPrefix)
+	for _, hash := range strings.Split(existing, "\n") {
+		known[strings.TrimSpace(hash)] = true
+	}
+
+	fallbackBranch, err := r.GetCheckpointBranch()
+	if err != nil {
+		fallbackBranch = detachedNamespace
+	}
+
+	for i, parts := range splitRecords(reflogOutput) {
+		if len(parts) < 3 || known[parts[0]] {
+			continue
+		}
+		cp, ok := ParseCheckpoint(parts[0], parts[2])
+		if !ok {
+			continue
+		}
+		known[parts[0]] = true
+
+		// Prefer the branch that still contains the checkpoint
+		branch := fallbackBranch
+		containing, err := r.RunCommand("for-each-ref", "--contains", parts[0], "--format=%(refname:short)", "refs/heads/")
+		if err == nil && strings.TrimSpace(containing) != "" {
+			branch = strings.TrimSpace(strings.SplitN(containing, "\n", 2)[0])
+		}
+
+		// Derive a sortable id from the commit time; the reflog is newest first, so
+		// subtracting its index keeps ids unique and newer checkpoints higher
+		seconds, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		id := strconv.FormatInt(seconds*int64(time.Second)-int64(i), 10)
+		if cp.ID != "" {
+			id = cp.ID
+		}
+
+		if err := r.recordCheckpointRef(branch, id, parts[0]); err != nil {
+			return err
+		}
+	}
+
+	// A dry run must not remember a migration that only happened on paper
+	if r.IsDryRun() {
+		return nil
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(marker, []byte(time.Now().Format(time.RFC3339)+"\n"), 0o644)
+}
@@ -0,0 +1,33 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrationOrdersLegacyCheckpointsFromTheSameSecond(t *testing.T) {
+	r := newTestRepo(t)
+	t.Setenv("GIT_COMMITTER_DATE", "1700000000 +0000")
+	for _, note := range []string{"first", "second"} {
+		r.write("app.txt", note+"\n")
+		r.git(r.dir, "add", "app.txt")
+		r.git(r.dir, "commit", "-q", "-m", "CHECKPOINT: 2023-11-14 22:13:20 - "+note)
+	}
+	stateDir, err := r.GetStateDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(filepath.Join(stateDir, migrationMarker))
+
+	if err := r.migrateReflogCheckpoints(); err != nil {
+		t.Fatal(err)
+	}
+
+	refs := r.git(r.dir, "for-each-ref", "--sort=refname", "--format=%(subject)", CheckpointRefPrefix)
+	want := "CHECKPOINT: 2023-11-14 22:13:20 - first\nCHECKPOINT: 2023-11-14 22:13:20 - second"
+	if !strings.HasSuffix(refs, want) {
+		t.Errorf("checkpoints in id order:\n%s\nwant them to end with:\n%s", refs, want)
+	}
+}
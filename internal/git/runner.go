@@ -0,0 +1,216 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Invocation describes a single git command
+type Invocation struct {
+	Args  []string
+	Dir   string    // working directory; empty means the process directory
+	Env   []string  // extra KEY=VALUE entries added to the process environment
+	Stdin io.Reader // optional standard input
+
+	// Interactive connects the command to the terminal, for prompts such as `git add --patch`
+	Interactive bool
+}
+
+// Result holds what a git command produced
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Runner executes git invocations. Implementations must return a non-nil
+// error when the command could not run or exited with a non-zero status.
+type Runner interface {
+	Run(ctx context.Context, inv Invocation) (Result, error)
+}
+
+// CommandError is returned when git exits with a non-zero status
+type CommandError struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *CommandError) Error() string {
+	msg := fmt.Sprintf("exit status %d", e.ExitCode)
+	if line := firstLine(e.Stderr); line != "" {
+		msg += ": " + line
+	}
+	return msg
+}
+
+// ExecRunner runs the real git binary
+type ExecRunner struct {
+	// Binary overrides the git executable; defaults to "git" on PATH
+	Binary string
+}
+
+// Run executes the invocation with os/exec
+func (r ExecRunner) Run(ctx context.Context, inv Invocation) (Result, error) {
+	binary := r.Binary
+	if binary == "" {
+		binary = "git"
+	}
+
+	cmd := exec.CommandContext(ctx, binary, inv.Args...)
+	cmd.Dir = inv.Dir
+	if len(inv.Env) > 0 {
+		cmd.Env = append(os.Environ(), inv.Env...)
+	}
+	cmd.Stdin = inv.Stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if inv.Interactive {
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = io.MultiWriter(&stdout, os.Stdout)
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+	}
+
+	err := cmd.Run()
+	result := Result{Stdout: stdout.String(), Stderr: stderr.String()}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+		err = &CommandError{Args: inv.Args, ExitCode: result.ExitCode, Stderr: result.Stderr}
+	default:
+		result.ExitCode = -1
+	}
+	return result, err
+}
+
+// Repo is a repository vibe-check works on. Every git command an operation
+// runs goes through its Runner, in its Dir and bound to its context, so
+// separate values - one per workspace repository, or a background refresh
+// next to an operation - never share settings.
+type Repo struct {
+	Runner  Runner          // runs every git command; nil means ExecRunner
+	Dir     string          // directory commands run in; empty means the process directory
+	Ctx     context.Context // cancels every command; nil means context.Background()
+	Timeout time.Duration   // limit for a single command; 0 disables it
+
+	// Scope of the run, set by SetScope. prefix is the directory vibe-check
+	// was started in, relative to the repository root, with a trailing slash
+	// ("" at the root).
+	scope  Scope
+	prefix string
+}
+
+// Open returns a Repo that runs git directly in dir
+func Open(dir string) *Repo {
+	return &Repo{Runner: ExecRunner{}, Dir: dir}
+}
+
+// At returns a Repo for another directory that runs git the same way, with
+// the default scope
+func (r *Repo) At(dir string) *Repo {
+	return &Repo{Runner: r.Runner, Dir: dir, Ctx: r.Ctx, Timeout: r.Timeout}
+}
+
+// RunnerOptions selects the wrappers installed around the exec runner
+type RunnerOptions struct {
+	DryRun bool
+	Trace  func(line string) // receives one line per command; nil disables tracing
+}
+
+// NewRunner returns an exec runner wrapped for tracing and dry runs, and the
+// dry-run recorder, which is nil when dry run is off
+func NewRunner(opts RunnerOptions) (Runner, *DryRunRunner) {
+	var r Runner = ExecRunner{}
+	if opts.Trace != nil {
+		r = &TraceRunner{Runner: r, Log: opts.Trace}
+	}
+
+	var dryRun *DryRunRunner
+	if opts.DryRun {
+		dryRun = &DryRunRunner{Runner: r}
+		r = dryRun
+	}
+	return r, dryRun
+}
+
+// Exec runs an invocation through the repo's runner, filling in its dir
+func (r *Repo) Exec(ctx context.Context, inv Invocation) (Result, error) {
+	runner := r.Runner
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	if inv.Dir == "" {
+		inv.Dir = r.Dir
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	return runner.Run(ctx, inv)
+}
+
+// RunCommandContext executes a git command bound to ctx. It returns trimmed
+// stdout on success, and stdout plus stderr when the command fails so callers
+// can diagnose the failure.
+func (r *Repo) RunCommandContext(ctx context.Context, args ...string) (string, error) {
+	return r.run(ctx, Invocation{Args: args})
+}
+
+// run executes an invocation and shapes its output like RunCommand
+func (r *Repo) run(ctx context.Context, inv Invocation) (string, error) {
+	result, err := r.Exec(ctx, inv)
+	if err != nil {
+		return strings.TrimSpace(result.Stdout + "\n" + result.Stderr), err
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// commandContext returns the context commands run under by default
+func (r *Repo) commandContext() context.Context {
+	if r.Ctx == nil {
+		return context.Background()
+	}
+	return r.Ctx
+}
+
+// FormatCommand renders git arguments as a copy-pasteable command line
+func FormatCommand(args []string) string {
+	parts := []string{"git"}
+	for _, arg := range args {
+		switch {
+		case strings.IndexFunc(arg, func(r rune) bool { return !unicode.IsPrint(r) }) >= 0:
+			// Control characters (e.g. format separators) use ANSI-C quoting
+			quoted := strconv.Quote(arg)
+			arg = "$'" + strings.ReplaceAll(quoted[1:len(quoted)-1], "'", `\'`) + "'"
+		case arg == "" || strings.ContainsAny(arg, " \"'$`\\|&;<>()*?{}"):
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"vibe-check/internal/config"
+	"vibe-check/internal/models"
+	"vibe-check/internal/scan"
+)
+
+// The scan runs before a checkpoint is created and before finalize pushes.
+// scan.mode decides whether findings block the operation or are only
+// reported alongside its result.
+
+// runScan scans with the configured mode. In block mode findings are
+// returned as a *scan.Error; in warn mode they are returned for display.
+func (r *Repo) runScan(stage string, files func() ([]scan.File, error)) ([]models.Finding, error) {
+	mode := config.String("scan.mode")
+	if mode == "off" {
+		return nil, nil
+	}
+
+	list, err := files()
+	if err != nil {
+		return nil, err
+	}
+	opts := scan.Options{MaxFileSize: config.Size("scan.max_file_size")}
+	if opts.Allowlist, err = r.loadAllowlist(); err != nil {
+		return nil, err
+	}
+	findings, err := scan.Run(list, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(findings) > 0 && mode == "block" {
+		return nil, &scan.Error{Stage: stage, Findings: findings}
+	}
+	return findings, nil
+}
+
+// loadAllowlist reads the scan.allowlist file, relative to the repository root
+func (r *Repo) loadAllowlist() (*scan.Allowlist, error) {
+	file := config.String("scan.allowlist")
+	if !filepath.IsAbs(file) {
+		root, err := r.RepoRoot()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(root, file)
+	}
+	return scan.LoadAllowlist(file)
+}
+
+// worktreeFiles lists the uncommitted files matching pathspecs, read from
+// the working tree. Deleted files are left out: there is nothing to scan.
+func (r *Repo) worktreeFiles(pathspecs []string) ([]scan.File, error) {
+	root, err := r.RepoRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	// Tracked files that differ from HEAD, or every tracked file on an unborn branch
+	tracked := []string{"diff", "--name-only", "--no-renames", "-z", "HEAD", "--"}
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		tracked = []string{"ls-files", "-z", "--"}
+	}
+	changed, err := r.RunCommand(append(tracked, pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := r.RunCommand(append([]string{"ls-files", "--others", "--exclude-standard", "-z", "--"}, pathspecs...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []scan.File
+	for _, name := range append(splitNul(changed), splitNul(untracked)...) {
+		full := filepath.Join(root, filepath.FromSlash(name))
+		info, err := os.Lstat(full)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, scan.File{
+			Path: name,
+			Size: info.Size(),
+			Read: func() ([]byte, error) { return os.ReadFile(full) },
+		})
+	}
+	return files, nil
+}
+
+// commitFiles lists the files added or changed between two commits, read
+// from to. An empty from means every file in to.
+func (r *Repo) commitFiles(from, to string) ([]scan.File, error) {
+	// One listing of to gives every blob with its size; submodules are commits and skipped
+	output, err := r.RunCommand("ls-tree", "-r", "-l", "-z", to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changed map[string]bool
+	if from != "" {
+		diff, err := r.RunCommand("diff", "--name-only", "--no-renames", "--diff-filter=d", "-z", from, to)
+		if err != nil {
+			return nil, err
+		}
+		changed = make(map[string]bool)
+		for _, name := range splitNul(diff) {
+			changed[name] = true
+		}
+	}
+
+	var files []scan.File
+	for _, entry := range splitNul(output) {
+		// <mode> <type> <object> <size>\t<path>
+		meta, name, _ := strings.Cut(entry, "\t")
+		fields := strings.Fields(meta)
+		if len(fields) != 4 || fields[1] != "blob" || (changed != nil && !changed[name]) {
+			continue
+		}
+		size, _ := strconv.ParseInt(fields[3], 10, 64)
+		object := fields[2]
+		files = append(files, scan.File{
+			Path: name,
+			Size: size,
+			Read: func() ([]byte, error) {
+				// RunCommand trims output, so read the blob through Exec
+				result, err := r.Exec(r.commandContext(), Invocation{Args: []string{"cat-file", "blob", object}})
+				return []byte(result.Stdout), err
+			},
+		})
+	}
+	return files, nil
+}
+
+// mergeFiles combines two file lists; a path in both keeps its entry from extra
+func mergeFiles(files, extra []scan.File) []scan.File {
+	seen := make(map[string]bool, len(extra))
+	for _, f := range extra {
+		seen[f.Path] = true
+	}
+	merged := append([]scan.File(nil), extra...)
+	for _, f := range files {
+		if !seen[f.Path] {
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
+
+// splitNul splits -z output into its entries
+func splitNul(output string) []string {
+	var entries []string
+	for _, entry := range strings.Split(output, "\x00") {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Scope decides which part of the working tree a checkpoint covers
+type Scope string
+
+const (
+	// ScopeRepo checkpoints every change in the repository, wherever vibe-check runs
+	ScopeRepo Scope = "repo"
+	// ScopeCwd only checkpoints changes under the directory vibe-check runs in
+	ScopeCwd Scope = "cwd"
+)
+
+// ParseScope parses the value of --scope
+func ParseScope(value string) (Scope, error) {
+	switch Scope(value) {
+	case ScopeRepo, ScopeCwd:
+		return Scope(value), nil
+	}
+	return "", fmt.Errorf("invalid scope %q (use repo or cwd)", value)
+}
+
+// SetScope resolves the repository root and runs every later git command
+// from there, so results no longer depend on the directory vibe-check was
+// started in. Outside a repository it only records the scope. Call it before
+// the Repo is shared.
+func (r *Repo) SetScope(s Scope) error {
+	root, err := r.RepoRoot()
+	if err != nil {
+		r.scope, r.prefix = s, ""
+		return nil
+	}
+	// An empty prefix is trimmed to "" by RunCommand, which is what the root needs
+	dir, err := r.RunCommand("rev-parse", "--show-prefix")
+	if err != nil {
+		return fmt.Errorf("failed to resolve the current directory: %v", err)
+	}
+
+	r.Dir, r.scope, r.prefix = root, s, dir
+	return nil
+}
+
+// GetScope returns the scope of the run
+func (r *Repo) GetScope() Scope {
+	if r.scope == "" {
+		return ScopeRepo
+	}
+	return r.scope
+}
+
+// StartDir returns the directory vibe-check runs as if started in: the -C
+// directory or the process directory, even after SetScope moved to the root
+func (r *Repo) StartDir() (string, error) {
+	if r.Dir == "" {
+		return os.Getwd()
+	}
+	return filepath.Join(r.Dir, filepath.FromSlash(r.prefix)), nil
+}
+
+// DescribeScope names the scope for display, e.g. "cwd (internal/)"
+func (r *Repo) DescribeScope() string {
+	if r.GetScope() == ScopeCwd && r.prefix != "" {
+		return fmt.Sprintf("%s (%s)", r.scope, r.prefix)
+	}
+	return string(r.GetScope())
+}
+
+// scopePathspec returns the pathspec checkpoints stage changes from
+func (r *Repo) scopePathspec() string {
+	if r.GetScope() == ScopeCwd {
+		return ":/" + r.prefix
+	}
+	return ":/"
+}
+
+// scopedPath reports whether a root-relative path falls within the scope
+func (r *Repo) scopedPath(file string) bool {
+	return r.GetScope() != ScopeCwd || strings.HasPrefix(file, r.prefix)
+}
+
+// userPathspec anchors a path given relative to the directory vibe-check was
+// started in at the repository root. Magic pathspecs such as ":/a.go" are
+// kept as they are.
+func (r *Repo) userPathspec(p string) string {
+	if strings.HasPrefix(p, ":") || filepath.IsAbs(p) {
+		return p
+	}
+	return ":/" + path.Join(r.prefix, p)
+}
+
+// HasChangesInScope returns true if there are uncommitted changes a
+// checkpoint would include
+func (r *Repo) HasChangesInScope() bool {
+	status, err := r.RunCommand("status", "--porcelain", "--", r.scopePathspec())
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(status) != ""
+}
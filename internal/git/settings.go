@@ -0,0 +1,43 @@
+package git
+
+import (
+	"strings"
+)
+
+// legacySettings maps the vibe-check.* git config keys older versions read
+// onto the settings that replaced them. git reports keys in lower case.
+var legacySettings = map[string]string{
+	"vibe-check.remote":                 "push.remote",
+	"vibe-check.branch":                 "push.branch",
+	"vibe-check.refspec":                "push.refspec",
+	"vibe-check.retention":              "finalize.retention",
+	"vibe-check.checkpointtemplate":     "messages.checkpoint_template",
+	"vibe-check.checkpointbodytemplate": "messages.checkpoint_body_template",
+	"vibe-check.finalizetemplate":       "messages.finalize_template",
+	"vibe-check.timeformat":             "messages.time_format",
+	"vibe-check.timezone":               "messages.timezone",
+}
+
+// LegacySettings returns the vibe-check.* git config of the current
+// repository, keyed by setting name. Unknown keys are ignored.
+func (r *Repo) LegacySettings() map[string]string {
+	settings := map[string]string{}
+
+	// -z keeps multi-line values, such as templates, intact
+	output, err := r.RunCommand("config", "-z", "--get-regexp", `^vibe-check\.`)
+	if err != nil {
+		return settings
+	}
+	for _, entry := range strings.Split(output, "\x00") {
+		key, value, _ := strings.Cut(entry, "\n")
+		if name, ok := legacySettings[key]; ok {
+			settings[name] = value
+		}
+	}
+	return settings
+}
+
+// RepoRoot returns the top-level directory of the current working tree
+func (r *Repo) RepoRoot() (string, error) {
+	return r.RunCommand("rev-parse", "--show-toplevel")
+}
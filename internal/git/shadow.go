@@ -0,0 +1,272 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// Shadow checkpoints snapshot the working tree and index without touching the
+// user's branch. Like `git stash`, each snapshot is a commit whose tree is the
+// working tree, with HEAD as first parent and a commit of the index as second
+// parent. Only the checkpoint ref points at it.
+
+// ErrUncapturedChanges is returned when restoring a shadow checkpoint would
+// overwrite work that no checkpoint holds
+var ErrUncapturedChanges = errors.New("you have uncommitted changes that are not in any checkpoint. Create a checkpoint first")
+
+// TrailerShadow marks a checkpoint commit as an off-branch snapshot
+const TrailerShadow = "Vibe-Shadow"
+
+// createShadowCheckpoint records the working tree and index under a checkpoint ref
+// and returns the snapshot commit
+func (r *Repo) createShadowCheckpoint(branch, id, message, body, note string) (string, error) {
+	// Snapshot the index as-is (fails on unresolved conflicts)
+	indexTree, err := r.RunCommand("write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot index: %v", err)
+	}
+
+	worktreeTree, err := r.snapshotWorktreeTree()
+	if err != nil {
+		return "", err
+	}
+
+	// Unborn branches have no HEAD to use as parent
+	var parentArgs []string
+	head, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD")
+	if err == nil && head != "" {
+		parentArgs = []string{"-p", head}
+	}
+
+	indexArgs := append([]string{"commit-tree", indexTree}, parentArgs...)
+	indexArgs = append(indexArgs, "-m", "index on "+branch+": "+message)
+	indexCommit, err := r.RunCommand(indexArgs...)
+	if err != nil {
+		return "", fmt.Errorf("failed to record index snapshot: %v", err)
+	}
+
+	shadowArgs := append([]string{"commit-tree", worktreeTree}, parentArgs...)
+	shadowArgs = append(shadowArgs, "-p", indexCommit)
+	messageArgs := checkpointMessageArgs(id, message, body, note)
+	messageArgs[len(messageArgs)-1] += "\n" + TrailerShadow + ": true"
+	shadowArgs = append(shadowArgs, messageArgs...)
+	shadowCommit, err := r.RunCommand(shadowArgs...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create shadow checkpoint: %v", err)
+	}
+
+	if err := r.recordCheckpointRef(branch, id, shadowCommit); err != nil {
+		return "", err
+	}
+	return shadowCommit, nil
+}
+
+// snapshotWorktreeTree writes a tree of the whole working tree (tracked and
+// untracked, honouring .gitignore) using a temporary index, leaving the real
+// index untouched
+func (r *Repo) snapshotWorktreeTree() (string, error) {
+	tmpIndex, err := r.newTempIndex()
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpIndex)
+
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+	if _, err := r.runCommandWithEnv(env, "add", "-A"); err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %v", err)
+	}
+
+	tree, err := r.runCommandWithEnv(env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to snapshot working tree: %v", err)
+	}
+	return tree, nil
+}
+
+// newTempIndex creates a copy of the real index under .git/vibe-check, so
+// trees can be built without touching the user's staging area. The caller
+// removes it.
+func (r *Repo) newTempIndex() (string, error) {
+	stateDir, err := r.GetStateDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(stateDir, "index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary index: %v", err)
+	}
+	tmpIndex := tmp.Name()
+	tmp.Close()
+
+	// Start from a copy of the real index so stat info is reused
+	indexPath, err := r.RunCommand("rev-parse", "--path-format=absolute", "--git-path", "index")
+	if err != nil {
+		os.Remove(tmpIndex)
+		return "", err
+	}
+	if data, err := os.ReadFile(indexPath); err == nil {
+		if err := os.WriteFile(tmpIndex, data, 0o644); err != nil {
+			os.Remove(tmpIndex)
+			return "", err
+		}
+	} else {
+		// No index yet - git refuses an empty index file
+		os.Remove(tmpIndex)
+	}
+	return tmpIndex, nil
+}
+
+// restoreShadowCheckpoint makes the working tree and index match a shadow
+// checkpoint. HEAD is only moved (detached) when the snapshot was taken on a
+// different commit than the current one.
+func (r *Repo) restoreShadowCheckpoint(cp models.Checkpoint) error {
+	currentTree, err := r.snapshotWorktreeTree()
+	if err != nil {
+		return err
+	}
+
+	// Refuse to overwrite work that is not captured anywhere
+	safe, err := r.isTreeCaptured(currentTree)
+	if err != nil {
+		return err
+	}
+	if !safe {
+		return ErrUncapturedChanges
+	}
+
+	base, indexCommit, err := r.shadowParents(cp.Hash)
+	if err != nil {
+		return err
+	}
+
+	// Merge in a temporary index, so the staging area stays as it was until
+	// the snapshot's is put in place at the very end
+	tmpIndex, err := r.newTempIndex()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpIndex)
+	env := []string{"GIT_INDEX_FILE=" + tmpIndex}
+
+	// Load the current state into the index, then two-way merge to the snapshot.
+	// This also removes files that only exist in the current state.
+	if _, err := r.runCommandWithEnv(env, "read-tree", currentTree); err != nil {
+		return fmt.Errorf("failed to prepare index: %v", err)
+	}
+	// Refresh stat info so the merge sees the working tree as up to date
+	r.runCommandWithEnv(env, "update-index", "-q", "--refresh")
+	if _, err := r.runCommandWithEnv(env, "read-tree", "-m", "-u", currentTree, cp.Hash); err != nil {
+		return fmt.Errorf("failed to restore working tree: %v", err)
+	}
+
+	// The working tree now holds the snapshot; put it and HEAD back if a later step fails
+	head, _ := r.RunCommand("rev-parse", "--verify", "-q", "HEAD")
+	headMoved := false
+	rollback := func() {
+		if headMoved {
+			r.RunCommand("update-ref", "--no-deref", "-m", "vibe-check: undo failed switch", "HEAD", head)
+		}
+		r.runCommandWithEnv(env, "read-tree", "-m", "-u", cp.Hash, currentTree)
+	}
+
+	// Move to the snapshot's base only if it differs from HEAD
+	if base != "" && base != head {
+		if _, err := r.RunCommand("update-ref", "--no-deref", "-m", "vibe-check: switch to shadow checkpoint", "HEAD", base); err != nil {
+			rollback()
+			return fmt.Errorf("failed to move HEAD to snapshot base: %v", err)
+		}
+		headMoved = true
+	}
+
+	// Finally restore the staging area exactly as it was snapshotted
+	if _, err := r.RunCommand("read-tree", indexCommit+"^{tree}"); err != nil {
+		rollback()
+		return fmt.Errorf("failed to restore index: %v", err)
+	}
+
+	return nil
+}
+
+// shadowParents returns the base commit and index commit of a shadow checkpoint
+func (r *Repo) shadowParents(hash string) (base, indexCommit string, err error) {
+	output, err := r.RunCommand("rev-list", "--parents", "-n", "1", hash)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read shadow checkpoint %s: %v", hash, err)
+	}
+
+	parents := strings.Fields(output)[1:]
+	switch len(parents) {
+	case 1:
+		// Snapshot taken on an unborn branch: only the index parent exists
+		return "", parents[0], nil
+	case 2:
+		return parents[0], parents[1], nil
+	}
+	return "", "", fmt.Errorf("%s is not a shadow checkpoint", hash)
+}
+
+// isTreeCaptured reports whether a working tree snapshot equals HEAD or any checkpoint
+func (r *Repo) isTreeCaptured(tree string) (bool, error) {
+	if headTree, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD^{tree}"); err == nil && headTree == tree {
+		return true, nil
+	}
+
+	output, err := r.RunCommand("for-each-ref", "--format=%(tree)", CheckpointRefPrefix)
+	if err != nil {
+		return false, err
+	}
+	for _, t := range strings.Split(output, "\n") {
+		if strings.TrimSpace(t) == tree {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetCurrentCheckpointHash returns the short hash of the checkpoint the user is on.
+// For committed checkpoints this is HEAD; for shadow checkpoints it is the
+// snapshot whose tree and base match the current working tree.
+func (r *Repo) GetCurrentCheckpointHash() (string, error) {
+	currentCommit, err := r.GetCurrentCommit()
+	if err != nil {
+		return "", err
+	}
+
+	if !r.HasUncommittedChanges() {
+		return currentCommit, nil
+	}
+
+	checkpoints, err := r.GetCheckpoints()
+	if err != nil {
+		return currentCommit, nil
+	}
+
+	var tree string
+	for _, cp := range checkpoints {
+		if !cp.Shadow {
+			continue
+		}
+		if tree == "" {
+			if tree, err = r.snapshotWorktreeTree(); err != nil {
+				return currentCommit, nil
+			}
+		}
+
+		base, _, err := r.shadowParents(cp.Hash)
+		if err != nil || !strings.HasPrefix(base, currentCommit) {
+			continue
+		}
+		if cpTree, err := r.RunCommand("rev-parse", cp.Hash+"^{tree}"); err == nil && cpTree == tree {
+			return cp.Hash, nil
+		}
+	}
+
+	return currentCommit, nil
+}
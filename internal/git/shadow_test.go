@@ -0,0 +1,73 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// shadowCheckpoint writes app.txt with part of it staged, snapshots it in a
+// shadow checkpoint and returns the checkpoint's hash
+func (r *testRepo) shadowCheckpoint() string {
+	r.t.Helper()
+	r.write("app.txt", "staged\n")
+	r.git(r.dir, "add", "app.txt")
+	r.write("app.txt", "staged\nunstaged\n")
+	cp, err := r.CreateCheckpointWithOptions(CheckpointOptions{Shadow: true})
+	if err != nil {
+		r.t.Fatalf("creating shadow checkpoint: %v", err)
+	}
+	return cp.Hash
+}
+
+func TestSwitchToShadowCheckpointRestoresIndex(t *testing.T) {
+	r := newTestRepo(t)
+	shadow := r.shadowCheckpoint()
+	r.git(r.dir, "checkout", "-q", "--", ".")
+	r.git(r.dir, "reset", "-q")
+	r.checkpoint("other\n")
+
+	if err := r.SwitchToCheckpoint(shadow); err != nil {
+		t.Fatalf("switching to shadow checkpoint: %v", err)
+	}
+	if got := r.git(r.dir, "show", ":app.txt"); got != "staged" {
+		t.Errorf("index holds %q, want the snapshot's staged content", got)
+	}
+	if data, _ := os.ReadFile(filepath.Join(r.dir, "app.txt")); string(data) != "staged\nunstaged\n" {
+		t.Errorf("working tree holds %q, want the snapshot's", data)
+	}
+}
+
+func TestFailedShadowRestoreLeavesIndexAndWorktree(t *testing.T) {
+	r := newTestRepo(t)
+	shadow := r.shadowCheckpoint()
+	r.git(r.dir, "checkout", "-q", "--", ".")
+	r.git(r.dir, "reset", "-q")
+	r.checkpoint("other\n")
+
+	// Stage something the working tree does not show: the restore must keep it
+	r.git(r.dir, "rm", "-q", "--cached", "README.md")
+	index := r.git(r.dir, "ls-files", "--stage")
+	before := r.snapshot()
+
+	// A held HEAD lock makes moving HEAD fail after the working tree was updated
+	lock := filepath.Join(r.dir, ".git", "HEAD.lock")
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err := r.SwitchToCheckpoint(shadow)
+	os.Remove(lock)
+
+	if err == nil {
+		t.Fatal("switch succeeded although HEAD is locked")
+	}
+	if got := r.git(r.dir, "ls-files", "--stage"); got != index {
+		t.Errorf("index changed by a failed restore:\n got %s\nwant %s", got, index)
+	}
+	if after := r.snapshot(); after != before {
+		t.Errorf("repository changed by a failed restore:\n got %+v\nwant %+v", after, before)
+	}
+	if data, _ := os.ReadFile(filepath.Join(r.dir, "app.txt")); string(data) != "other\n" {
+		t.Errorf("working tree holds %q, want it unchanged", data)
+	}
+}
@@ -0,0 +1,153 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// stateFile is the key=value file (inside the state dir) holding vibe-check's session state
+const stateFile = "state"
+
+// stateOriginBranch is the state key for the branch the user was on before switching to a checkpoint
+const stateOriginBranch = "origin-branch"
+
+// readState loads the state file; a missing file is an empty state
+func (r *Repo) readState() (map[string]string, error) {
+	state := make(map[string]string)
+
+	stateDir, err := r.GetStateDir()
+	if err != nil {
+		return state, err
+	}
+	data, err := os.ReadFile(filepath.Join(stateDir, stateFile))
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(key) != "" {
+			state[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return state, nil
+}
+
+// updateState sets (or, for empty values, removes) keys in the state file
+func (r *Repo) updateState(changes map[string]string) error {
+	// A dry run must not remember a switch that only happened on paper
+	if r.IsDryRun() {
+		return nil
+	}
+
+	state, err := r.readState()
+	if err != nil {
+		return err
+	}
+	for key, value := range changes {
+		if value == "" {
+			delete(state, key)
+		} else {
+			state[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(state))
+	for key := range state {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var data strings.Builder
+	for _, key := range keys {
+		data.WriteString(key + "=" + state[key] + "\n")
+	}
+
+	stateDir, err := r.GetStateDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(stateDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(stateDir, stateFile), []byte(data.String()), 0o644)
+}
+
+// GetOriginBranch returns the branch a switch to a checkpoint started from.
+// It is empty unless HEAD is detached and the recorded branch still exists.
+func (r *Repo) GetOriginBranch() string {
+	branch, err := r.GetCurrentBranch()
+	if err != nil || branch != "HEAD" {
+		return ""
+	}
+
+	state, err := r.readState()
+	if err != nil || state[stateOriginBranch] == "" {
+		return ""
+	}
+	origin := state[stateOriginBranch]
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", "refs/heads/"+origin); err != nil {
+		return ""
+	}
+	return origin
+}
+
+// recordOriginBranch remembers the branch a switch leaves, unless one is already
+// recorded for the detached HEAD we are on
+func (r *Repo) recordOriginBranch() error {
+	branch, err := r.GetCurrentBranch()
+	if err != nil {
+		return err
+	}
+	if branch == "HEAD" {
+		// Switching between checkpoints keeps the original branch
+		return nil
+	}
+	return r.updateState(map[string]string{stateOriginBranch: branch})
+}
+
+// clearOriginBranch forgets the recorded branch once the user is back on it
+func (r *Repo) clearOriginBranch() error {
+	return r.updateState(map[string]string{stateOriginBranch: ""})
+}
+
+// ErrNoOriginBranch is returned by ReturnToOriginBranch when no switch recorded a branch
+var ErrNoOriginBranch = errors.New("no original branch recorded for this detached HEAD")
+
+// ReturnToOriginBranch checks out the branch recorded by the last switch and
+// returns its name. Uncommitted changes are carried over when git allows it.
+func (r *Repo) ReturnToOriginBranch() (string, error) {
+	if !r.IsRepo() {
+		return "", ErrNotRepo
+	}
+
+	current, err := r.GetCurrentBranch()
+	if err != nil {
+		return "", fmt.Errorf("error getting current branch: %v", err)
+	}
+	if current != "HEAD" {
+		return "", fmt.Errorf("already on branch %s", current)
+	}
+
+	origin := r.GetOriginBranch()
+	if origin == "" {
+		return "", fmt.Errorf("%w. Checkout a branch with: git checkout <branch>", ErrNoOriginBranch)
+	}
+
+	op := r.beginOperation(OpReturn)
+	if output, err := r.RunCommand("checkout", origin); err != nil {
+		return "", fmt.Errorf("failed to return to %s: %v\n%s", origin, err, output)
+	}
+	if err := r.clearOriginBranch(); err != nil {
+		return origin, fmt.Errorf("returned to %s but failed to update state: %v", origin, err)
+	}
+	op.finish("return to " + origin)
+	return origin, nil
+}
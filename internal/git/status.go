@@ -0,0 +1,192 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// fileStatusOrder is the order uncommitted file groups are reported in
+var fileStatusOrder = []string{"conflicted", "staged", "modified", "deleted", "untracked"}
+
+// GetStatus reports the current branch or detached state, the current
+// checkpoint, how many checkpoints are pending finalize, uncommitted files,
+// ahead/behind counts against the push target and any backup branches
+func (r *Repo) GetStatus() (*models.Status, error) {
+	return r.getStatus(true)
+}
+
+// GetHeaderStatus is GetStatus for frequent refreshes. It never snapshots the
+// working tree, so a shadow checkpoint the working tree matches is not
+// recognised as the current checkpoint.
+func (r *Repo) GetHeaderStatus() (*models.Status, error) {
+	return r.getStatus(false)
+}
+
+func (r *Repo) getStatus(matchShadow bool) (*models.Status, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+
+	status := &models.Status{}
+
+	branch, err := r.GetCurrentBranch()
+	if err != nil {
+		// Unborn branches have no HEAD commit to abbreviate
+		if branch, err = r.RunCommand("symbolic-ref", "--short", "HEAD"); err != nil {
+			return nil, fmt.Errorf("error getting current branch: %v", err)
+		}
+	}
+	if branch == "HEAD" {
+		status.Detached = true
+		status.OriginBranch = r.GetOriginBranch()
+	} else {
+		status.Branch = branch
+	}
+
+	checkpoints, err := r.GetCheckpoints()
+	if err != nil {
+		return nil, err
+	}
+	current, _ := r.GetCurrentCommit()
+	if matchShadow {
+		current, _ = r.GetCurrentCheckpointHash()
+	}
+	for i, cp := range checkpoints {
+		if cp.ID == "" {
+			continue
+		}
+		status.Pending++
+		if cp.Hash == current && status.Current == nil {
+			status.Current = &checkpoints[i]
+		}
+	}
+
+	if r.HasUncommittedChanges() {
+		if status.Changes, err = r.uncommittedFiles(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Count against where finalize would push
+	if target, err := r.ResolvePushTarget(); err == nil {
+		remoteRef := "refs/remotes/" + target.Remote + "/" + target.RemoteBranch
+		if _, err := r.RunCommand("rev-parse", "--verify", "-q", remoteRef); err == nil {
+			counts, err := r.RunCommand("rev-list", "--left-right", "--count", "HEAD..."+remoteRef)
+			if fields := strings.Fields(counts); err == nil && len(fields) == 2 {
+				status.Upstream = target.Remote + "/" + target.RemoteBranch
+				status.Ahead, _ = strconv.Atoi(fields[0])
+				status.Behind, _ = strconv.Atoi(fields[1])
+			}
+		}
+	}
+
+	backups, err := r.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		status.Backups = append(status.Backups, b.Name)
+	}
+
+	return status, nil
+}
+
+// uncommittedFiles groups the output of `git status --porcelain -z` by status.
+// A file staged and then modified again appears in both groups, and a rename
+// lists its source as a file of its own.
+func (r *Repo) uncommittedFiles() ([]models.FileGroup, error) {
+	// RunCommand trims output, which would eat the leading status column.
+	// -z keeps paths with spaces or non-ASCII characters unquoted.
+	result, err := r.Exec(r.commandContext(), Invocation{Args: []string{"status", "--porcelain", "-z"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check git status: %v", err)
+	}
+
+	files := make(map[string][]string)
+	entries := strings.Split(result.Stdout, "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		x, y, path := entry[0], entry[1], entry[3:]
+
+		// The source of a rename or copy follows as the next entry
+		if (x == 'R' || x == 'C' || y == 'R' || y == 'C') && i+1 < len(entries) {
+			i++
+			if source := entries[i]; source != "" && x == 'R' {
+				files["staged"] = append(files["staged"], source)
+			}
+		}
+
+		switch {
+		case x == '?' && y == '?':
+			files["untracked"] = append(files["untracked"], path)
+		case x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D'):
+			files["conflicted"] = append(files["conflicted"], path)
+		default:
+			if x != ' ' {
+				files["staged"] = append(files["staged"], path)
+			}
+			if y == 'D' {
+				files["deleted"] = append(files["deleted"], path)
+			} else if y != ' ' {
+				files["modified"] = append(files["modified"], path)
+			}
+		}
+	}
+
+	var groups []models.FileGroup
+	for _, name := range fileStatusOrder {
+		if len(files[name]) > 0 {
+			groups = append(groups, models.FileGroup{Status: name, Files: files[name]})
+		}
+	}
+	return groups, nil
+}
+
+// FormatStatus renders a status report for display
+func FormatStatus(status *models.Status) string {
+	var s strings.Builder
+
+	switch {
+	case !status.Detached:
+		s.WriteString("On branch " + status.Branch + "\n")
+	case status.OriginBranch != "":
+		s.WriteString("Detached from branch " + status.OriginBranch + " (run `vibe-check return` to go back)\n")
+	default:
+		s.WriteString("Detached HEAD\n")
+	}
+
+	if status.Current != nil {
+		s.WriteString(fmt.Sprintf("Current checkpoint: [%s] %s\n", status.Current.Hash, status.Current.Message))
+	} else {
+		s.WriteString("Current checkpoint: none\n")
+	}
+	s.WriteString(fmt.Sprintf("Pending finalize: %d checkpoint(s)\n", status.Pending))
+
+	if status.Upstream != "" {
+		s.WriteString(fmt.Sprintf("Upstream %s: %d ahead, %d behind\n", status.Upstream, status.Ahead, status.Behind))
+	} else {
+		s.WriteString("Upstream: none\n")
+	}
+
+	if len(status.Backups) > 0 {
+		s.WriteString(fmt.Sprintf("Backups: %d (newest %s)\n", len(status.Backups), status.Backups[0]))
+	}
+
+	if len(status.Changes) == 0 {
+		s.WriteString("\nWorking tree clean")
+		return s.String()
+	}
+	s.WriteString("\nUncommitted changes:")
+	for _, group := range status.Changes {
+		s.WriteString(fmt.Sprintf("\n  %s:", group.Status))
+		for _, file := range group.Files {
+			s.WriteString("\n    " + file)
+		}
+	}
+	return s.String()
+}
@@ -0,0 +1,119 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"vibe-check/internal/config"
+	"vibe-check/internal/models"
+)
+
+// ResolvePushTarget works out where finalize commits and pushes. Each field
+// is taken from the push.* settings (--remote, --branch and --refspec on the
+// command line), then the current branch's upstream, then sensible defaults.
+func (r *Repo) ResolvePushTarget() (models.PushTarget, error) {
+	branchOverride := config.String("push.branch")
+	refspecOverride := config.String("push.refspec")
+
+	var target models.PushTarget
+	current, err := r.GetCurrentBranch()
+	if err == nil && current != "HEAD" {
+		target.Branch = current
+	}
+
+	target.Remote = config.String("push.remote")
+	if target.Remote == "" {
+		target.Remote = r.defaultPushRemote(target.Branch)
+	}
+
+	if target.Branch == "" {
+		// Detached HEAD - commit to the configured branch, or the one the user switched from
+		target.Branch = firstNonEmpty(branchOverride, r.detachedTargetBranch())
+		if target.Branch == "" {
+			return target, fmt.Errorf("cannot determine the branch to finalize onto from a detached HEAD. " +
+				"Run `vibe-check return`, checkout a branch, or pass --branch <name>")
+		}
+		branchOverride = ""
+	}
+
+	target.RemoteBranch = firstNonEmpty(branchOverride, r.upstreamBranch(target.Branch, target.Remote), target.Branch)
+
+	target.Refspec = refspecOverride
+	if target.Refspec == "" {
+		target.Refspec = target.Branch
+		if target.RemoteBranch != target.Branch {
+			target.Refspec = target.Branch + ":refs/heads/" + target.RemoteBranch
+		}
+	}
+
+	return target, nil
+}
+
+// defaultPushRemote picks the remote git itself would push branch to
+func (r *Repo) defaultPushRemote(branch string) string {
+	if branch != "" {
+		if remote := r.gitConfig("branch." + branch + ".pushRemote"); remote != "" {
+			return remote
+		}
+	}
+	if remote := r.gitConfig("remote.pushDefault"); remote != "" {
+		return remote
+	}
+	if branch != "" {
+		// "." means the upstream is a local branch - there is nothing to push to
+		if remote := r.gitConfig("branch." + branch + ".remote"); remote != "" && remote != "." {
+			return remote
+		}
+	}
+
+	// Fall back to the only remote when there is exactly one, else push.fallback_remote
+	remotes, err := r.RunCommand("remote")
+	if err == nil {
+		names := strings.Fields(remotes)
+		if len(names) == 1 {
+			return names[0]
+		}
+	}
+	return config.String("push.fallback_remote")
+}
+
+// upstreamBranch returns the remote branch branch tracks on remote, if any
+func (r *Repo) upstreamBranch(branch, remote string) string {
+	if r.gitConfig("branch."+branch+".remote") != remote {
+		return ""
+	}
+	return strings.TrimPrefix(r.gitConfig("branch."+branch+".merge"), "refs/heads/")
+}
+
+// detachedTargetBranch returns the existing branch a detached HEAD belongs to:
+// the one recorded by the last switch, else the checkpoint's namespace
+func (r *Repo) detachedTargetBranch() string {
+	if origin := r.GetOriginBranch(); origin != "" {
+		return origin
+	}
+	branch, err := r.GetCheckpointBranch()
+	if err != nil || branch == detachedNamespace {
+		return ""
+	}
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", "refs/heads/"+branch); err != nil {
+		return ""
+	}
+	return branch
+}
+
+// gitConfig returns a git config value, or "" when it is not set
+func (r *Repo) gitConfig(key string) string {
+	value, err := r.RunCommand("config", "--get", key)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
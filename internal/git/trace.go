@@ -0,0 +1,38 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TraceRunner logs every git invocation with its duration and exit status
+type TraceRunner struct {
+	Runner Runner
+	Log    func(line string)
+}
+
+// Run executes the invocation through the wrapped runner and logs it
+func (t *TraceRunner) Run(ctx context.Context, inv Invocation) (Result, error) {
+	start := time.Now()
+	result, err := t.Runner.Run(ctx, inv)
+	elapsed := time.Since(start)
+
+	status := fmt.Sprintf("exit %d", result.ExitCode)
+	if err != nil && result.ExitCode <= 0 {
+		status = "error: " + err.Error()
+	}
+
+	if t.Log != nil {
+		t.Log(fmt.Sprintf("[trace] %s (%s, %s)", FormatCommand(inv.Args), formatDuration(elapsed), status))
+	}
+	return result, err
+}
+
+// formatDuration renders short durations in a compact, readable form
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,123 @@
+package git
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"vibe-check/internal/models"
+)
+
+// Checkpoint commits are marked with structured trailers:
+//
+//	CHECKPOINT: 03/08/2025 14:30 - Testing auth
+//
+//	Vibe-Checkpoint: 1754231400000000000
+//	Vibe-Note: Testing auth
+const (
+	TrailerCheckpoint = "Vibe-Checkpoint"
+	TrailerNote       = "Vibe-Note"
+
+	// legacyCheckpointPrefix marks checkpoints created before trailers were introduced
+	legacyCheckpointPrefix = "CHECKPOINT:"
+)
+
+// Record and field separators used when asking git for full commit messages
+const (
+	recordSep = "\x1e"
+	fieldSep  = "\x1f"
+)
+
+// ParseCheckpoint turns a full commit message into a checkpoint.
+// ok is false when the message does not belong to a checkpoint commit.
+func ParseCheckpoint(hash, message string) (cp models.Checkpoint, ok bool) {
+	message = strings.TrimSpace(message)
+	subject := strings.SplitN(message, "\n", 2)[0]
+	cp = models.Checkpoint{Hash: hash, Message: subject}
+
+	trailers := parseTrailers(message)
+	if id, found := trailers[TrailerCheckpoint]; found {
+		cp.ID = id
+		cp.Note = trailers[TrailerNote]
+		cp.Shadow = trailers[TrailerShadow] == "true"
+		return cp, true
+	}
+
+	// Backward compatibility: "CHECKPOINT: <timestamp>[ - <note>]"
+	if strings.HasPrefix(subject, legacyCheckpointPrefix) {
+		rest := strings.TrimSpace(strings.TrimPrefix(subject, legacyCheckpointPrefix))
+		if i := strings.Index(rest, " - "); i >= 0 {
+			cp.Note = strings.TrimSpace(rest[i+3:])
+		}
+		return cp, true
+	}
+
+	return cp, false
+}
+
+// IsCheckpointMessage reports whether a full commit message marks a checkpoint
+func IsCheckpointMessage(message string) bool {
+	_, ok := ParseCheckpoint("", message)
+	return ok
+}
+
+// checkpointMessageArgs returns the `git commit -m` arguments for a checkpoint.
+// The trailers always come last; body may be empty.
+func checkpointMessageArgs(id, subject, body, note string) []string {
+	trailers := TrailerCheckpoint + ": " + id
+	if note != "" {
+		trailers += "\n" + TrailerNote + ": " + singleLine(note)
+	}
+	args := []string{"-m", subject}
+	if body != "" {
+		args = append(args, "-m", body)
+	}
+	return append(args, "-m", trailers)
+}
+
+// parseTrailers extracts "Key: value" trailers from the last paragraph of a message
+func parseTrailers(message string) map[string]string {
+	trailers := make(map[string]string)
+
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	// A message with a single paragraph has only a subject, never trailers
+	if len(paragraphs) < 2 {
+		return trailers
+	}
+
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		key, value, found := strings.Cut(line, ": ")
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			continue
+		}
+		trailers[key] = strings.TrimSpace(value)
+	}
+
+	return trailers
+}
+
+// singleLine collapses a note into one line so it stays a valid trailer value
+func singleLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// splitRecords splits git output produced with recordSep/fieldSep separators
+func splitRecords(output string) [][]string {
+	var records [][]string
+	for _, record := range strings.Split(output, recordSep) {
+		record = strings.TrimLeft(record, "\n")
+		if strings.TrimSpace(record) == "" {
+			continue
+		}
+		records = append(records, strings.Split(record, fieldSep))
+	}
+	return records
+}
+
+// parseUnix turns a unix timestamp field into a time; zero when it is malformed
+func parseUnix(field string) time.Time {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+	if err != nil {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0)
+}
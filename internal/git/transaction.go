@@ -0,0 +1,186 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"vibe-check/internal/models"
+)
+
+// backupBranchPrefix names the branches finalize keeps as a safety net
+const backupBranchPrefix = "vibe-check-backup-"
+
+// backupOriginKey is the branch config key recording which branch a backup was taken from
+const backupOriginKey = "vibeCheckOrigin"
+
+// FinalizeError reports a failed finalize and what was done to recover from it
+type FinalizeError struct {
+	Step        string   // step that failed, e.g. "pushing"
+	Err         error    // the failure itself
+	Restored    []string // what the rollback put back
+	RollbackErr error    // set when the rollback itself failed
+	Backup      string   // backup branch kept for manual recovery
+}
+
+func (e *FinalizeError) Error() string {
+	var s strings.Builder
+	s.WriteString(fmt.Sprintf("finalize failed while %s: %v\n", e.Step, e.Err))
+
+	if e.RollbackErr != nil {
+		s.WriteString(fmt.Sprintf("\nRollback failed: %v\n", e.RollbackErr))
+		if e.Backup != "" {
+			s.WriteString(fmt.Sprintf("Your work is safe on branch %s. Restore it with:\ngit reset --hard %s\n", e.Backup, e.Backup))
+		}
+		return strings.TrimRight(s.String(), "\n")
+	}
+
+	s.WriteString("\nRolled back:\n")
+	for _, line := range e.Restored {
+		s.WriteString("  - " + line + "\n")
+	}
+	if e.Backup != "" {
+		s.WriteString(fmt.Sprintf("\nBackup kept on branch %s (delete it with: git branch -D %s)\n", e.Backup, e.Backup))
+	}
+	return strings.TrimRight(s.String(), "\n")
+}
+
+func (e *FinalizeError) Unwrap() error {
+	return e.Err
+}
+
+// finalizeTx snapshots the repository before finalize so every failure can be
+// rolled back, and keeps a backup branch until finalize is confirmed
+type finalizeTx struct {
+	repo   *Repo
+	before models.RepoState
+	refs   map[string]string
+	backup string
+	// untracked holds the untracked files before finalize
+	untracked map[string]bool
+	// snapshotted is false during dry runs, where nothing needs rolling back
+	snapshotted bool
+}
+
+// beginFinalizeTx snapshots the repository and creates the backup branch
+func (r *Repo) beginFinalizeTx() (*finalizeTx, error) {
+	tx := &finalizeTx{repo: r}
+
+	if !r.IsDryRun() {
+		state, refs, err := r.snapshotRepo()
+		if err != nil {
+			return nil, fmt.Errorf("cannot snapshot repository before finalize: %v", err)
+		}
+		untracked, err := r.untrackedFiles()
+		if err != nil {
+			return nil, fmt.Errorf("cannot snapshot repository before finalize: %v", err)
+		}
+		tx.before, tx.refs, tx.untracked, tx.snapshotted = state, refs, untracked, true
+	}
+
+	// Unborn branches have nothing to back up
+	if _, err := r.RunCommand("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		return tx, nil
+	}
+
+	backup := fmt.Sprintf("%s%d", backupBranchPrefix, time.Now().Unix())
+	if _, err := r.RunCommand("branch", backup, "HEAD"); err != nil {
+		return nil, fmt.Errorf("failed to create backup: %v", err)
+	}
+	tx.backup = backup
+
+	// Remember the branch the backup belongs to; git drops this with the branch
+	if origin, err := r.GetCheckpointBranch(); err == nil {
+		r.RunCommand("config", "branch."+backup+"."+backupOriginKey, origin)
+	}
+	return tx, nil
+}
+
+// commit confirms finalize and deletes the backup branch
+func (tx *finalizeTx) commit() error {
+	if tx.backup == "" {
+		return nil
+	}
+	if _, err := tx.repo.RunCommand("branch", "-D", tx.backup); err != nil {
+		return fmt.Errorf("finalize succeeded but the backup branch %s could not be deleted: %v", tx.backup, err)
+	}
+	return nil
+}
+
+// rollback restores HEAD, refs, the index and the working tree to how they
+// were before finalize. The backup branch is kept either way.
+func (tx *finalizeTx) rollback(step string, cause error) error {
+	ferr := &FinalizeError{Step: step, Err: cause, Backup: tx.backup}
+	if !tx.snapshotted {
+		ferr.Restored = []string{"nothing (dry run)"}
+		return ferr
+	}
+
+	current, refs, err := tx.repo.snapshotRepo()
+	if err != nil {
+		ferr.RollbackErr = fmt.Errorf("cannot read repository state: %v", err)
+		return ferr
+	}
+
+	// Put every ref back, except the backup branch which must survive
+	var changes []models.RefChange
+	for _, change := range diffRefs(refs, tx.refs) {
+		if change.Ref != "refs/heads/"+tx.backup {
+			changes = append(changes, change)
+		}
+	}
+
+	if len(changes) == 0 && current == tx.before {
+		ferr.Restored = []string{"nothing had changed yet"}
+		return ferr
+	}
+	// Files finalize staged from untracked stay on disk
+	kept, err := tx.repo.keptFiles(tx.before.Worktree, current.Worktree, tx.untracked)
+	if err != nil {
+		ferr.RollbackErr = err
+		return ferr
+	}
+	if err := tx.repo.restoreRepoState(current, tx.before, changes, kept); err != nil {
+		ferr.RollbackErr = err
+		return ferr
+	}
+	ferr.Restored = describeRestore(current, tx.before, changes)
+	return ferr
+}
+
+// describeRestore lists what moving from one state to another put back
+func describeRestore(from, to models.RepoState, changes []models.RefChange) []string {
+	var restored []string
+
+	checkpoints := 0
+	for _, change := range changes {
+		switch {
+		case strings.HasPrefix(change.Ref, CheckpointRefPrefix):
+			checkpoints++
+		case strings.HasPrefix(change.Ref, "refs/heads/") && change.New != "":
+			restored = append(restored, fmt.Sprintf("branch %s reset to %s", strings.TrimPrefix(change.Ref, "refs/heads/"), shortHash(change.New)))
+		}
+	}
+	if checkpoints > 0 {
+		restored = append(restored, fmt.Sprintf("%d checkpoint ref(s) restored", checkpoints))
+	}
+
+	if from.Branch != to.Branch || from.Head != to.Head {
+		if to.Branch != "" {
+			restored = append(restored, "HEAD back on branch "+to.Branch)
+		} else {
+			restored = append(restored, "HEAD detached at "+shortHash(to.Head)+" again")
+		}
+	}
+	if from.Worktree != to.Worktree || from.Index != to.Index {
+		restored = append(restored, "working tree and staging area restored")
+	}
+	return restored
+}
+
+// shortHash abbreviates a full commit hash for display
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
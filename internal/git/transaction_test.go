@@ -0,0 +1,199 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testRepo is a repository with a bare remote, both in temporary directories
+type testRepo struct {
+	*Repo
+	t      *testing.T
+	dir    string
+	remote string
+}
+
+// newTestRepo creates a repository on branch main whose first commit is
+// pushed to a bare origin, with a Repo running git in it
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	// Keep the user's git and vibe-check settings out of the test
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	r := &testRepo{t: t, dir: t.TempDir(), remote: filepath.Join(t.TempDir(), "origin.git")}
+	r.git("", "init", "-q", "--bare", "-b", "main", r.remote)
+	r.git("", "init", "-q", "-b", "main", r.dir)
+	r.git(r.dir, "config", "user.name", "Test")
+	r.git(r.dir, "config", "user.email", "test@example.com")
+	r.write("README.md", "hello\n")
+	r.git(r.dir, "add", "-A")
+	r.git(r.dir, "commit", "-q", "-m", "init")
+	r.git(r.dir, "remote", "add", "origin", r.remote)
+	r.git(r.dir, "push", "-q", "-u", "origin", "main")
+
+	r.Repo = Open(r.dir)
+	return r
+}
+
+// git runs a git command in dir and returns its trimmed output
+func (r *testRepo) git(dir string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+func (r *testRepo) write(name, content string) {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// checkpoint changes a file and creates a checkpoint
+func (r *testRepo) checkpoint(content string) {
+	r.t.Helper()
+	r.write("app.txt", content)
+	if _, err := r.CreateCheckpointWithOptions(CheckpointOptions{}); err != nil {
+		r.t.Fatalf("creating checkpoint: %v", err)
+	}
+}
+
+// rejectPushes installs a pre-receive hook on the remote that declines every push
+func (r *testRepo) rejectPushes() {
+	r.t.Helper()
+	hook := filepath.Join(r.remote, "hooks", "pre-receive")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\necho rejected by test >&2\nexit 1\n"), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// repoSnapshot captures what a failed finalize must leave untouched
+type repoSnapshot struct {
+	head, branch, refs, status string
+}
+
+func (r *testRepo) snapshot() repoSnapshot {
+	r.t.Helper()
+	return repoSnapshot{
+		head:   r.git(r.dir, "rev-parse", "HEAD"),
+		branch: r.git(r.dir, "symbolic-ref", "-q", "HEAD"),
+		refs:   r.git(r.dir, "for-each-ref", "--format=%(refname) %(objectname)", CheckpointRefPrefix),
+		status: r.git(r.dir, "status", "--porcelain"),
+	}
+}
+
+// backups lists the backup branches finalize left behind
+func (r *testRepo) backups() []string {
+	r.t.Helper()
+	return strings.Fields(r.git(r.dir, "for-each-ref", "--format=%(refname:short)", "refs/heads/"+backupBranchPrefix+"*"))
+}
+
+// assertRolledBack checks that a failed finalize put the repository back and kept the backup
+func (r *testRepo) assertRolledBack(err error, before repoSnapshot) {
+	r.t.Helper()
+	var ferr *FinalizeError
+	if !errors.As(err, &ferr) {
+		r.t.Fatalf("expected a *FinalizeError, got %v", err)
+	}
+	if ferr.Step != "pushing" {
+		r.t.Errorf("failed step = %q, want pushing", ferr.Step)
+	}
+	if ferr.RollbackErr != nil {
+		r.t.Fatalf("rollback failed: %v", ferr.RollbackErr)
+	}
+	if len(ferr.Restored) == 0 {
+		r.t.Error("the error does not report what was restored")
+	}
+
+	after := r.snapshot()
+	if after.head != before.head {
+		r.t.Errorf("HEAD = %s, want %s", after.head, before.head)
+	}
+	if after.branch != before.branch {
+		r.t.Errorf("checked out %q, want %q", after.branch, before.branch)
+	}
+	if after.refs != before.refs {
+		r.t.Errorf("checkpoint refs not restored:\n got %s\nwant %s", after.refs, before.refs)
+	}
+	if after.status != before.status {
+		r.t.Errorf("working tree not restored:\n got %q\nwant %q", after.status, before.status)
+	}
+
+	backups := r.backups()
+	if len(backups) != 1 || backups[0] != ferr.Backup {
+		r.t.Fatalf("backup branches = %v, want [%s]", backups, ferr.Backup)
+	}
+	if got := r.git(r.dir, "rev-parse", ferr.Backup); got != before.head {
+		r.t.Errorf("backup points at %s, want %s", got, before.head)
+	}
+}
+
+func TestFinalizeRollsBackWhenPushIsRejected(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("one\n")
+	r.checkpoint("two\n")
+	r.rejectPushes()
+	remoteBefore := r.git(r.remote, "rev-parse", "main")
+	before := r.snapshot()
+
+	_, err := r.FinalizeAndPushWithMessage("Ship it")
+
+	r.assertRolledBack(err, before)
+	if got := r.git(r.remote, "rev-parse", "main"); got != remoteBefore {
+		t.Errorf("remote moved to %s", got)
+	}
+}
+
+func TestFinalizeRollsBackWhenRemoteDiverged(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("one\n")
+
+	// Someone else pushes, so the lease on origin/main no longer holds
+	other := t.TempDir()
+	r.git("", "clone", "-q", r.remote, other)
+	r.git(other, "-c", "user.name=Other", "-c", "user.email=other@example.com", "commit", "-q", "--allow-empty", "-m", "theirs")
+	r.git(other, "push", "-q", "origin", "main")
+	before := r.snapshot()
+
+	_, err := r.FinalizeAndPushWithMessage("Ship it")
+
+	r.assertRolledBack(err, before)
+	if got := r.git(r.remote, "log", "-1", "--format=%s", "main"); got != "theirs" {
+		t.Errorf("remote commit overwritten, now %q", got)
+	}
+}
+
+func TestFinalizeDeletesBackupOncePushed(t *testing.T) {
+	r := newTestRepo(t)
+	r.checkpoint("one\n")
+	r.checkpoint("two\n")
+
+	if _, err := r.FinalizeAndPushWithMessage("Ship it"); err != nil {
+		t.Fatalf("finalize failed: %v", err)
+	}
+	if backups := r.backups(); len(backups) != 0 {
+		t.Errorf("backup branches left after a confirmed push: %v", backups)
+	}
+	if got := r.git(r.remote, "log", "-1", "--format=%s", "main"); got != "Ship it" {
+		t.Errorf("remote head is %q, want the squashed commit", got)
+	}
+	if refs := r.snapshot().refs; refs != "" {
+		t.Errorf("checkpoint refs left after finalize: %s", refs)
+	}
+}
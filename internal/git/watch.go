@@ -0,0 +1,151 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"vibe-check/internal/config"
+	"vibe-check/internal/models"
+)
+
+// ErrWatchLimit is returned once a watcher has made watch.max_count checkpoints
+var ErrWatchLimit = errors.New("reached the maximum number of automatic checkpoints")
+
+// autoNoteFiles is how many changed files an automatic checkpoint note names
+const autoNoteFiles = 3
+
+// WatchOptions controls automatic checkpoints
+type WatchOptions struct {
+	Quiet       time.Duration // how long the working tree must stay unchanged
+	MinInterval time.Duration // minimum time between two automatic checkpoints
+	MaxCount    int           // stop after this many checkpoints; 0 means no limit
+	Poll        time.Duration // how often the working tree is checked
+}
+
+// WatchOptionsFromConfig reads the watch.* settings
+func WatchOptionsFromConfig() WatchOptions {
+	return WatchOptions{
+		Quiet:       config.Duration("watch.quiet_period"),
+		MinInterval: config.Duration("watch.min_interval"),
+		MaxCount:    config.Int("watch.max_count"),
+		Poll:        config.Duration("watch.poll_interval"),
+	}
+}
+
+// Watcher creates a checkpoint once the working tree has changed and then
+// stayed unchanged for the quiet period. Changes are detected by snapshotting
+// the working tree, so ignored files never trigger a checkpoint.
+type Watcher struct {
+	repo      *Repo
+	opts      WatchOptions
+	tree      string    // working tree seen by the last poll
+	changedAt time.Time // when tree last changed
+	lastMade  time.Time // when the last automatic checkpoint was made
+	count     int
+}
+
+// NewWatcher starts watching the working tree as it is now
+func (r *Repo) NewWatcher(opts WatchOptions) (*Watcher, error) {
+	if !r.IsRepo() {
+		return nil, ErrNotRepo
+	}
+	tree, err := r.snapshotWorktreeTree()
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{repo: r, opts: opts, tree: tree, changedAt: time.Now()}, nil
+}
+
+// Count returns how many checkpoints the watcher has made
+func (w *Watcher) Count() int {
+	return w.count
+}
+
+// Poll checks the working tree once and creates a checkpoint when it is due.
+// It returns the checkpoint, or nil when none was made.
+func (w *Watcher) Poll(now time.Time) (*models.Checkpoint, error) {
+	if w.opts.MaxCount > 0 && w.count >= w.opts.MaxCount {
+		return nil, ErrWatchLimit
+	}
+
+	tree, err := w.repo.snapshotWorktreeTree()
+	if err != nil {
+		return nil, err
+	}
+	if tree != w.tree {
+		w.tree, w.changedAt = tree, now
+		return nil, nil
+	}
+	if now.Sub(w.changedAt) < w.opts.Quiet {
+		return nil, nil
+	}
+	if !w.lastMade.IsZero() && now.Sub(w.lastMade) < w.opts.MinInterval {
+		return nil, nil
+	}
+
+	// Nothing to do while the working tree matches HEAD
+	head, err := w.repo.RunCommand("rev-parse", "--verify", "-q", "HEAD^{tree}")
+	if err == nil && head == tree {
+		return nil, nil
+	}
+
+	note, err := w.repo.autoCheckpointNote()
+	if err != nil {
+		return nil, err
+	}
+	cp, err := w.repo.CreateCheckpointWithOptions(CheckpointOptions{Note: note})
+	if errors.Is(err, ErrNoChanges) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	w.lastMade = now
+	w.count++
+	return cp, nil
+}
+
+// Watch polls until the context of git commands is cancelled, calling
+// onCheckpoint for every checkpoint made. It stops with ErrWatchLimit once
+// MaxCount checkpoints were made.
+func (r *Repo) Watch(opts WatchOptions, onCheckpoint func(*models.Checkpoint)) error {
+	w, err := r.NewWatcher(opts)
+	if err != nil {
+		return err
+	}
+
+	ctx := r.commandContext()
+	ticker := time.NewTicker(opts.Poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			cp, err := w.Poll(now)
+			if err != nil {
+				// An interrupted git command is not a failure
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if cp != nil {
+				onCheckpoint(cp)
+			}
+		}
+	}
+}
+
+// autoCheckpointNote names the changed files, e.g. "auto: a.go, b.go, c.go and 2 more"
+func (r *Repo) autoCheckpointNote() (string, error) {
+	files, err := r.ChangedFiles()
+	if err != nil {
+		return "", err
+	}
+	if len(files) <= autoNoteFiles {
+		return "auto: " + strings.Join(files, ", "), nil
+	}
+	return fmt.Sprintf("auto: %s and %d more", strings.Join(files[:autoNoteFiles], ", "), len(files)-autoNoteFiles), nil
+}
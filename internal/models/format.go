@@ -0,0 +1,85 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// RelativeTime describes t relative to now, e.g. "5 min ago" or "yesterday".
+// Anything older than a week is shown as a date.
+func RelativeTime(t, now time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	age := now.Sub(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%d min ago", int(age.Minutes()))
+	case age < 2*time.Hour:
+		return "1 hour ago"
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%d hours ago", int(age.Hours()))
+	case DayLabel(t, now) == "Yesterday":
+		return "yesterday"
+	case age < 7*24*time.Hour:
+		return fmt.Sprintf("%d days ago", int(age.Hours()/24))
+	}
+	return t.Format("02 Jan 2006")
+}
+
+// DayLabel names the calendar day of t for grouping lists: "Today",
+// "Yesterday" or the date
+func DayLabel(t, now time.Time) string {
+	if t.IsZero() {
+		return "Unknown date"
+	}
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, t.Location())
+	switch {
+	case day.Equal(today):
+		return "Today"
+	case day.Equal(today.AddDate(0, 0, -1)):
+		return "Yesterday"
+	}
+	return t.Format("Mon 02 Jan 2006")
+}
+
+// ChangeSize summarises a checkpoint's change counts, e.g. "+12 -3 in 2 files"
+func (c Checkpoint) ChangeSize() string {
+	if c.FilesChanged == 0 {
+		return "no changes"
+	}
+	files := "files"
+	if c.FilesChanged == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("+%d -%d in %d %s", c.Insertions, c.Deletions, c.FilesChanged, files)
+}
+
+// ShortStat renders the change counts the way `git diff --shortstat` does,
+// e.g. "2 files changed, 5 insertions(+)". It is empty when nothing changed.
+func (c Checkpoint) ShortStat() string {
+	if c.FilesChanged == 0 {
+		return ""
+	}
+	stat := fmt.Sprintf("%d %s changed", c.FilesChanged, plural(c.FilesChanged, "file", "files"))
+	// Like git, a change without line counts (e.g. a binary file) shows both zeros
+	if c.Insertions > 0 || c.Deletions == 0 {
+		stat += fmt.Sprintf(", %d %s(+)", c.Insertions, plural(c.Insertions, "insertion", "insertions"))
+	}
+	if c.Deletions > 0 || c.Insertions == 0 {
+		stat += fmt.Sprintf(", %d %s(-)", c.Deletions, plural(c.Deletions, "deletion", "deletions"))
+	}
+	return stat
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
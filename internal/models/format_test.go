@@ -0,0 +1,22 @@
+package models
+
+import "testing"
+
+func TestShortStatMatchesGit(t *testing.T) {
+	cases := []struct {
+		files, insertions, deletions int
+		want                         string
+	}{
+		{0, 0, 0, ""},
+		{1, 1, 0, "1 file changed, 1 insertion(+)"},
+		{2, 5, 3, "2 files changed, 5 insertions(+), 3 deletions(-)"},
+		{1, 0, 1, "1 file changed, 1 deletion(-)"},
+		{1, 0, 0, "1 file changed, 0 insertions(+), 0 deletions(-)"},
+	}
+	for _, c := range cases {
+		cp := Checkpoint{FilesChanged: c.files, Insertions: c.insertions, Deletions: c.deletions}
+		if got := cp.ShortStat(); got != c.want {
+			t.Errorf("ShortStat(%d, %d, %d) = %q, want %q", c.files, c.insertions, c.deletions, got, c.want)
+		}
+	}
+}
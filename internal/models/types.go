@@ -9,9 +9,14 @@ const (
 	StateMenu AppState = iota
 	StateCheckpointCreation
 	StateCheckpointNoteInput
+	StateCheckpointFileSelection
 	StateCheckpointSelection
 	StateFinalizeOptions
 	StateFinalizeMessageInput
+	StateFinalizeConfirm
+	StateHistory
+	StateBackups
+	StateDiff
 	StateExecuting
 	StateResult
 )
@@ -19,8 +24,128 @@ const (
 // Checkpoint represents a git checkpoint
 type Checkpoint struct {
 	Hash    string
+	SHA     string // full commit hash
 	Message string
-	Time    time.Time
+	Time    time.Time // committer date
+
+	// Filled in by git.LoadCheckpointDetails
+	Author       string
+	AuthorTime   time.Time
+	FilesChanged int
+	Insertions   int
+	Deletions    int
+
+	// ID is the checkpoint identifier within its branch namespace.
+	// It is empty for entries that are not checkpoints (e.g. the last regular commit).
+	ID     string
+	Ref    string // full ref name under refs/vibe-check/
+	Branch string // branch namespace the checkpoint belongs to
+	Note   string // custom note from the Vibe-Note trailer
+	Shadow bool   // off-branch snapshot that never moved the user's branch
+
+	Findings []Finding // what the scan warned about when the checkpoint was created
+}
+
+// Finding is something the pre-checkpoint scan flagged, such as an API key or a large file
+type Finding struct {
+	Path    string // root-relative path of the file
+	Line    int    // line of the match; 0 for findings about the whole file
+	Rule    string // rule that matched, e.g. aws-access-key or large-file
+	Message string // what was found, without the secret itself
+}
+
+// PushTarget describes where finalized work is committed and pushed
+type PushTarget struct {
+	Remote       string // remote name, e.g. origin or upstream
+	Branch       string // local branch finalize commits to
+	RemoteBranch string // branch updated on the remote
+	Refspec      string // refspec passed to git push
+}
+
+// FinalizePlan describes what finalizing the current checkpoint would do
+type FinalizePlan struct {
+	Squash      []Checkpoint // current checkpoint first, then the older ones squashed with it
+	Drop        []Checkpoint // newer checkpoints that would be discarded
+	BaseCommit  string       // commit the squashed commit is created on top of
+	BaseMessage string
+	Target      PushTarget
+	DiffStat    string       // combined diffstat of the squashed commit
+	Findings    []Finding    // what the scan before pushing warned about
+}
+
+// Backup is a branch finalize kept as a safety net
+type Backup struct {
+	Name     string    // branch name, vibe-check-backup-<unix>
+	Hash     string    // short hash of the commit it points at
+	Time     time.Time // when finalize created it
+	Branch   string    // branch it was taken from; empty if unknown
+	DiffStat string    // summary of its difference from HEAD
+}
+
+// Status summarises where the user is: branch, checkpoint and pending work
+type Status struct {
+	Branch       string      // checked out branch; empty when HEAD is detached
+	Detached     bool
+	OriginBranch string      // branch a switch started from, when detached
+	Current      *Checkpoint // checkpoint the user is on, if any
+	Pending      int         // checkpoints waiting to be finalized
+	Changes      []FileGroup // uncommitted files grouped by status
+	Upstream     string      // remote branch ahead/behind are counted against; empty if none
+	Ahead        int
+	Behind       int
+	Backups      []string // backup branches left by finalize
+}
+
+// FileGroup lists uncommitted files that share a status
+type FileGroup struct {
+	Status string // conflicted, staged, modified, deleted or untracked
+	Files  []string
+}
+
+// Diff is the difference between two checkpoints, or a checkpoint and the working tree
+type Diff struct {
+	From   string // description of the older side
+	To     string // description of the newer side
+	Output string // git diff output in the requested mode
+}
+
+// GCPlan previews what `vibe-check gc` removes
+type GCPlan struct {
+	ReflogEntries      int          // reflog entries expired across all refs
+	ExpiredCheckpoints []string     // finalized checkpoint refs past their retention
+	UnreachableObjects int          // objects pruned once the reflogs are gone
+	UnreachableCommits []Checkpoint // commits among them, for review
+}
+
+// Operation is one entry of the operation journal
+type Operation struct {
+	ID      string      `json:"id"`
+	Kind    string      `json:"kind"` // create, switch, return, finalize, restore, prune, recover, undo or redo
+	Time    time.Time   `json:"time"`
+	Summary string      `json:"summary"`
+	Before  RepoState   `json:"before"`
+	After   RepoState   `json:"after"`
+	Refs    []RefChange `json:"refs,omitempty"`
+	Target  string      `json:"target,omitempty"` // undo/redo: ID of the operation reverted or replayed
+	Kept    []string    `json:"kept,omitempty"`   // files it started or stopped tracking; undo and redo leave them on disk
+
+	Undone bool `json:"-"` // set when listing history: reverted and not replayed since
+}
+
+// RepoState is a snapshot of HEAD, the index and the tracked files
+type RepoState struct {
+	Head     string `json:"head"`             // commit HEAD points at; empty on an unborn branch
+	Branch   string `json:"branch,omitempty"` // checked out branch; empty when HEAD is detached
+	Origin   string `json:"origin,omitempty"` // branch recorded by the last switch
+	Index    string `json:"index"`            // tree of the staging area; empty while it has conflicts
+	Worktree string `json:"worktree"`         // tree of the tracked files as they are in the working tree
+}
+
+// RefChange records a ref an operation created, moved or deleted
+type RefChange struct {
+	Ref string `json:"ref"`
+	Old string `json:"old,omitempty"` // empty when the ref was created
+	New string `json:"new,omitempty"` // empty when the ref was deleted
 }
 
 // AppModel represents the main application model for Bubble Tea
@@ -38,27 +163,82 @@ type AppModel struct {
 	CheckpointOptions       []string
 	CheckpointOptionsCursor int
 	CustomNote              string
+	PendingPaths            []string // paths the note being typed is for; nil for a full checkpoint
+
+	// File picker for partial checkpoints
+	Files        []string // uncommitted files, root-relative
+	FileSelected []bool
+	FileCursor   int
 
 	// Checkpoint selection
 	Checkpoints       []Checkpoint
 	CheckpointCursor  int
+	CurrentCheckpoint string // hash of the checkpoint the user is on
+	OriginBranch      string // branch a switch to a detached checkpoint started from
+	Status            *Status // header panel; nil when it could not be read
+	GroupByDay        bool    // show checkpoints under day headings
+
+	// Operation history
+	History       []Operation // newest first
+	HistoryCursor int
+
+	// Diff viewer
+	DiffTitle  string
+	DiffLines  []string
+	DiffOffset int // first line shown
+
+	// Terminal size, from the last window size message
+	Width  int
+	Height int
+
+	// Backup branches left by finalize
+	Backups      []Backup // newest first
+	BackupCursor int
 
 	// Finalize options
 	FinalizeOptions       []string
 	FinalizeOptionsCursor int
 	CustomCommitMessage   string
 
+	// Finalize confirmation
+	FinalizePlan         *FinalizePlan
+	PendingCommitMessage string // message finalize will use once confirmed
+	PendingNoPush        bool   // finalize locally without pushing
+	ConfirmOptions       []string
+	ConfirmCursor        int
+
 	// Execution state
 	Loading     bool
 	LoadingText string
+	DryRun      bool   // plan git commands instead of running them
+	Trace       bool   // show every git command run by an operation
+	Scope       string // what checkpoints cover, e.g. "repo" or "cwd (internal/)"
+
+	// Auto-checkpoint watcher
+	AutoCheckpoint       bool
+	AutoCheckpoints      int    // checkpoints it made since it was switched on
+	AutoCheckpointStatus string // what it last did, shown under the toggles
 
 	// Result display
-	Result  string
-	IsError bool
+	Result   string
+	IsError  bool
+	Findings []Finding // scan warnings about what the operation committed or pushed
 }
 
 // Result represents an operation result
 type Result struct {
 	Content string
 	IsError bool
-}
\ No newline at end of file
+}
+
+// RepoResult is the outcome of a workspace operation in one repository
+type RepoResult struct {
+	Repo        string        // path of the repository, as listed in workspace.repos
+	Checkpoint  *Checkpoint   // checkpoint created
+	Checkpoints []Checkpoint  // checkpoints listed
+	Plan        *FinalizePlan // what finalize squashed
+	Pushed      bool
+	Skipped     string // why nothing was done, e.g. "no changes"; empty otherwise
+	RolledBack  bool   // its change was undone because another repository failed
+	Err         error
+}
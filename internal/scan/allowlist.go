@@ -0,0 +1,88 @@
+package scan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// Allowlist holds the findings the user accepted. Each line of the file is
+// a path pattern, optionally followed by the rule it applies to:
+//
+//	# test fixtures hold fake keys
+//	testdata/*
+//	config/.env.local env-file
+//	assets/video.mp4 large-file
+//
+// Patterns use path.Match syntax against the root-relative path; a pattern
+// ending in "/" covers everything under that directory.
+type Allowlist struct {
+	entries []allowEntry
+}
+
+type allowEntry struct {
+	pattern string
+	rule    string // empty for every rule
+}
+
+// LoadAllowlist reads an allowlist file. A missing file is an empty allowlist.
+func LoadAllowlist(file string) (*Allowlist, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return &Allowlist{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	list := &Allowlist{}
+	lines := bufio.NewScanner(f)
+	for n := 1; lines.Scan(); n++ {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("%s:%d: expected a path pattern and an optional rule", file, n)
+		}
+		entry := allowEntry{pattern: fields[0]}
+		if len(fields) == 2 {
+			entry.rule = fields[1]
+		}
+		if _, err := path.Match(entry.pattern, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid pattern %q", file, n, entry.pattern)
+		}
+		list.entries = append(list.entries, entry)
+	}
+	if err := lines.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Allows reports whether the finding is covered by an entry
+func (a *Allowlist) Allows(f models.Finding) bool {
+	if a == nil {
+		return false
+	}
+	for _, e := range a.entries {
+		if e.rule != "" && e.rule != f.Rule {
+			continue
+		}
+		if strings.HasSuffix(e.pattern, "/") {
+			if strings.HasPrefix(f.Path, e.pattern) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(e.pattern, f.Path); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package scan
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"vibe-check/internal/models"
+)
+
+// Rule is a built-in check. A rule matches either the file path or a line
+// of the content.
+type Rule struct {
+	ID          string
+	Description string
+	Path        *regexp.Regexp // matched against the root-relative path
+	Content     *regexp.Regexp // matched against each line
+}
+
+// DefaultRules catch common tokens, private keys and environment files
+var DefaultRules = []Rule{
+	{ID: "env-file", Description: "environment file, which usually holds secrets",
+		Path: regexp.MustCompile(`(^|/)\.env(\.[^/]+)?$`)},
+	{ID: "private-key", Description: "private key",
+		Content: regexp.MustCompile(`-----BEGIN ((RSA|DSA|EC|OPENSSH|PGP|ENCRYPTED) )?PRIVATE KEY( BLOCK)?-----`)},
+	{ID: "aws-access-key", Description: "AWS access key ID",
+		Content: regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{ID: "github-token", Description: "GitHub token",
+		Content: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{ID: "slack-token", Description: "Slack token",
+		Content: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{ID: "stripe-key", Description: "Stripe live key",
+		Content: regexp.MustCompile(`\b[rs]k_live_[A-Za-z0-9]{20,}\b`)},
+	{ID: "google-api-key", Description: "Google API key",
+		Content: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{ID: "api-secret-key", Description: "API secret key (sk-...)",
+		Content: regexp.MustCompile(`\bsk-[A-Za-z0-9_\-]{32,}`)},
+}
+
+// envTemplates are environment files meant to be committed
+var envTemplates = []string{".env.example", ".env.sample", ".env.template", ".env.dist"}
+
+// SecretScanner applies rules to paths and text content. Binary files and
+// files above MaxSize are only checked by path.
+type SecretScanner struct {
+	Rules   []Rule
+	MaxSize int64
+}
+
+func (s SecretScanner) Name() string {
+	return "secrets"
+}
+
+func (s SecretScanner) Scan(f File) ([]models.Finding, error) {
+	var findings []models.Finding
+	var contentRules []Rule
+	for _, rule := range s.Rules {
+		switch {
+		case rule.Path != nil:
+			if rule.Path.MatchString(f.Path) && !isEnvTemplate(f.Path) {
+				findings = append(findings, models.Finding{Path: f.Path, Rule: rule.ID, Message: rule.Description})
+			}
+		case rule.Content != nil:
+			contentRules = append(contentRules, rule)
+		}
+	}
+	if len(contentRules) == 0 || (s.MaxSize > 0 && f.Size > s.MaxSize) {
+		return findings, nil
+	}
+
+	content, err := f.Read()
+	if err != nil {
+		return nil, err
+	}
+	if isBinary(content) {
+		return findings, nil
+	}
+	for i, line := range strings.Split(string(content), "\n") {
+		for _, rule := range contentRules {
+			if rule.Content.MatchString(line) {
+				findings = append(findings, models.Finding{Path: f.Path, Line: i + 1, Rule: rule.ID, Message: rule.Description})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// isEnvTemplate reports whether path is an example environment file
+func isEnvTemplate(p string) bool {
+	base := path.Base(p)
+	for _, name := range envTemplates {
+		if base == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SizeScanner reports files larger than Max bytes
+type SizeScanner struct {
+	Max int64
+}
+
+func (s SizeScanner) Name() string {
+	return "size"
+}
+
+func (s SizeScanner) Scan(f File) ([]models.Finding, error) {
+	if f.Size <= s.Max {
+		return nil, nil
+	}
+	return []models.Finding{{
+		Path:    f.Path,
+		Rule:    "large-file",
+		Message: fmt.Sprintf("large file (%s, limit %s)", formatSize(f.Size), formatSize(s.Max)),
+	}}, nil
+}
+
+// formatSize renders a size with the largest whole unit, e.g. "12.3MB"
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
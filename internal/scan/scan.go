@@ -0,0 +1,129 @@
+// Package scan looks for secrets and large files in what is about to be
+// checkpointed or pushed. Scanners are pluggable: the built-in secret and
+// size scanners always run, and Register adds more.
+package scan
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"vibe-check/internal/models"
+)
+
+// File is one file to scan. Content is read lazily, so scanners that only
+// look at the path or size never load large files.
+type File struct {
+	Path string // root-relative path
+	Size int64
+	Read func() ([]byte, error)
+}
+
+// Scanner checks one file and reports what it finds
+type Scanner interface {
+	Name() string
+	Scan(f File) ([]models.Finding, error)
+}
+
+// Options configures a scan
+type Options struct {
+	MaxFileSize int64      // files above this size are reported; 0 disables the check
+	Allowlist   *Allowlist // findings it matches are dropped; may be nil
+}
+
+var (
+	mu         sync.RWMutex
+	registered []Scanner
+)
+
+// Register adds a scanner that runs after the built-in ones
+func Register(s Scanner) {
+	mu.Lock()
+	defer mu.Unlock()
+	registered = append(registered, s)
+}
+
+// scanners returns the built-in scanners followed by the registered ones
+func scanners(opts Options) []Scanner {
+	mu.RLock()
+	defer mu.RUnlock()
+	list := []Scanner{SecretScanner{Rules: DefaultRules, MaxSize: opts.MaxFileSize}}
+	if opts.MaxFileSize > 0 {
+		list = append(list, SizeScanner{Max: opts.MaxFileSize})
+	}
+	return append(list, registered...)
+}
+
+// Run scans every file and returns the findings the allowlist does not
+// cover, ordered by path and line
+func Run(files []File, opts Options) ([]models.Finding, error) {
+	var findings []models.Finding
+	list := scanners(opts)
+	for _, f := range files {
+		f.Read = cached(f.Read)
+		for _, s := range list {
+			found, err := s.Scan(f)
+			if err != nil {
+				return nil, fmt.Errorf("%s scanner failed on %s: %v", s.Name(), f.Path, err)
+			}
+			for _, finding := range found {
+				if !opts.Allowlist.Allows(finding) {
+					findings = append(findings, finding)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Line < findings[j].Line
+	})
+	return findings, nil
+}
+
+// cached reads the content once, however many scanners ask for it
+func cached(read func() ([]byte, error)) func() ([]byte, error) {
+	var once sync.Once
+	var data []byte
+	var err error
+	return func() ([]byte, error) {
+		once.Do(func() { data, err = read() })
+		return data, err
+	}
+}
+
+// isBinary reports whether content looks like a binary file, the way git decides
+func isBinary(content []byte) bool {
+	head := content
+	if len(head) > 8000 {
+		head = head[:8000]
+	}
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// Error is returned when scan.mode is block and the scan found something
+type Error struct {
+	Stage    string // what was stopped, e.g. "checkpoint" or "push"
+	Findings []models.Finding
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s blocked: the scan found %d problem(s)\n%s\n\nRemove them, list them in the allowlist file, or set scan.mode to warn",
+		e.Stage, len(e.Findings), Format(e.Findings))
+}
+
+// Format renders findings one per line, e.g. "config/.env: env file (env-file)"
+func Format(findings []models.Finding) string {
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		location := f.Path
+		if f.Line > 0 {
+			location = fmt.Sprintf("%s:%d", f.Path, f.Line)
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s (%s)", location, f.Message, f.Rule))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -22,6 +22,13 @@ func RenderMenu(m models.AppModel) string {
 
 	var menu strings.Builder
 	
+	// Where the user is, and where a switch to a checkpoint left them
+	if m.Status != nil {
+		menu.WriteString(renderStatusPanel(m.Status) + "\n\n")
+	} else if m.OriginBranch != "" {
+		menu.WriteString(AppCaption.Render("Detached from branch ") + MenuItem.Render(m.OriginBranch) + "\n\n")
+	}
+	
 	// Menu items
 	for i, choice := range m.MenuChoices {
 		prefix := "  "
@@ -51,8 +58,16 @@ func RenderMenu(m models.AppModel) string {
 		menu.WriteString("\n")
 	}
 	
+	// Mode toggles
+	menu.WriteString("\n" + renderToggle("d", "dry run", m.DryRun) + "  " + renderToggle("t", "trace", m.Trace) +
+		"  " + renderToggle("a", "auto-checkpoint", m.AutoCheckpoint) + "\n")
+	menu.WriteString(AppCaption.Render("scope: "+m.Scope) + "\n")
+	if m.AutoCheckpointStatus != "" {
+		menu.WriteString(AppCaption.Render("auto-checkpoint: "+m.AutoCheckpointStatus) + "\n")
+	}
+	
 	// Footer
-	footer := HelpStyle.Render("↑/↓ navigate • Enter select • q quit")
+	footer := HelpStyle.Render("↑/↓ navigate • Enter select • d/t/a toggle • q quit")
 	dividerLine := Hairline.Render(strings.Repeat("─", 40))
 	
 	body := strings.TrimRight(menu.String(), "\n") + "\n" + dividerLine + "\n" + footer
@@ -134,4 +149,55 @@ func RenderFinalizeOptions(m models.AppModel) string {
 	s.WriteString(Card.Render(body))
 	
 	return s.String()
-}
\ No newline at end of file
+}
+
+// renderStatusPanel renders the branch, checkpoint and pending work above the menu
+func renderStatusPanel(status *models.Status) string {
+	var lines []string
+	
+	location := AppCaption.Render("On branch ") + MenuItem.Render(status.Branch)
+	if status.Detached {
+		location = AppCaption.Render("Detached HEAD")
+		if status.OriginBranch != "" {
+			location = AppCaption.Render("Detached from branch ") + MenuItem.Render(status.OriginBranch)
+		}
+	}
+	if status.Upstream != "" {
+		location += AppCaption.Render(fmt.Sprintf("  ↑%d ↓%d %s", status.Ahead, status.Behind, status.Upstream))
+	}
+	lines = append(lines, location)
+	
+	checkpoint := AppCaption.Render("No current checkpoint")
+	if status.Current != nil {
+		checkpoint = AppCaption.Render("Checkpoint ") + MenuItem.Render("["+status.Current.Hash+"]")
+		if status.Current.Note != "" {
+			checkpoint += " " + AppCaption.Render(status.Current.Note)
+		}
+	}
+	lines = append(lines, checkpoint+AppCaption.Render(fmt.Sprintf("  •  %d pending finalize", status.Pending)))
+	
+	if len(status.Changes) > 0 {
+		var counts []string
+		for _, group := range status.Changes {
+			counts = append(counts, fmt.Sprintf("%d %s", len(group.Files), group.Status))
+		}
+		lines = append(lines, AppCaption.Render("Changes: "+strings.Join(counts, ", ")))
+	} else {
+		lines = append(lines, AppCaption.Render("Working tree clean"))
+	}
+	
+	if len(status.Backups) > 0 {
+		lines = append(lines, DisabledReasonStyle.Render(fmt.Sprintf("%d backup branch(es) - see Backups", len(status.Backups))))
+	}
+	
+	return strings.Join(lines, "\n")
+}
+
+// renderToggle renders a keyboard toggle with its on/off state
+func renderToggle(key, label string, on bool) string {
+	state := HelpStyle.Render("off")
+	if on {
+		state = SuccessStyle.Render("on")
+	}
+	return HelpStyle.Render(key+" "+label+": ") + state
+}
@@ -1,6 +1,10 @@
 package ui
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"vibe-check/internal/config"
+
+	"github.com/charmbracelet/lipgloss"
+)
 
 // Color palette - dark neutral base with cyan accent
 var (
@@ -28,6 +32,66 @@ const (
 
 // Header styles
 var (
+	AppTitle     lipgloss.Style
+	AppCaption   lipgloss.Style
+	TitleDivider lipgloss.Style
+)
+
+// Card/panel styles
+var (
+	Card    lipgloss.Style
+	CardAlt lipgloss.Style
+)
+
+// Menu styles
+var (
+	MenuPointer            lipgloss.Style
+	MenuItem               lipgloss.Style
+	MenuItemActive         lipgloss.Style
+	CurrentPointer         lipgloss.Style
+	CurrentCheckpointStyle lipgloss.Style
+)
+
+// Status styles
+var (
+	InfoStyle           lipgloss.Style
+	SuccessStyle        lipgloss.Style
+	ErrorStyle          lipgloss.Style
+	LoadingTextStyle    lipgloss.Style
+	HelpStyle           lipgloss.Style
+	DisabledStyle       lipgloss.Style
+	DisabledReasonStyle lipgloss.Style
+	Hairline            lipgloss.Style
+)
+
+// Diff viewer styles
+var (
+	DiffFileStyle lipgloss.Style
+	DiffMetaStyle lipgloss.Style
+	DiffHunkStyle lipgloss.Style
+	DiffAddStyle  lipgloss.Style
+	DiffDelStyle  lipgloss.Style
+)
+
+func init() {
+	buildStyles()
+}
+
+// ApplyConfig takes the ui.colors.* settings into the palette and rebuilds
+// the styles that use it
+func ApplyConfig() {
+	ColorAccent = lipgloss.Color(config.String("ui.colors.accent"))
+	ColorText = lipgloss.Color(config.String("ui.colors.text"))
+	ColorMuted = lipgloss.Color(config.String("ui.colors.muted"))
+	ColorInfo = lipgloss.Color(config.String("ui.colors.info"))
+	ColorSuccess = lipgloss.Color(config.String("ui.colors.success"))
+	ColorError = lipgloss.Color(config.String("ui.colors.error"))
+	buildStyles()
+}
+
+// buildStyles creates every style from the current palette
+func buildStyles() {
+	// Header styles
 	AppTitle = lipgloss.NewStyle().
 		Foreground(ColorText).
 		Bold(true)
@@ -37,10 +101,8 @@ var (
 
 	TitleDivider = lipgloss.NewStyle().
 		Foreground(ColorBorder)
-)
 
-// Card/panel styles
-var (
+	// Card/panel styles
 	Card = lipgloss.NewStyle().
 		Padding(1, 2).
 		Background(ColorPanel).
@@ -54,10 +116,8 @@ var (
 		Foreground(ColorText).
 		Border(lipgloss.RoundedBorder()).
 		BorderForeground(ColorBorder)
-)
 
-// Menu styles
-var (
+	// Menu styles
 	MenuPointer = lipgloss.NewStyle().
 		Foreground(ColorAccent).
 		Bold(true)
@@ -77,10 +137,8 @@ var (
 	CurrentCheckpointStyle = lipgloss.NewStyle().
 		Foreground(ColorSuccess).
 		Bold(true)
-)
 
-// Status styles
-var (
+	// Status styles
 	InfoStyle = lipgloss.NewStyle().
 		Foreground(ColorInfo).
 		Bold(true)
@@ -108,4 +166,21 @@ var (
 
 	Hairline = lipgloss.NewStyle().
 		Foreground(ColorBorder)
-)
\ No newline at end of file
+
+	// Diff viewer styles
+	DiffFileStyle = lipgloss.NewStyle().
+		Foreground(ColorText).
+		Bold(true)
+
+	DiffMetaStyle = lipgloss.NewStyle().
+		Foreground(ColorMuted2)
+
+	DiffHunkStyle = lipgloss.NewStyle().
+		Foreground(ColorAccent)
+
+	DiffAddStyle = lipgloss.NewStyle().
+		Foreground(ColorSuccess)
+
+	DiffDelStyle = lipgloss.NewStyle().
+		Foreground(ColorError)
+}
@@ -4,12 +4,21 @@ import (
 	"fmt"
 	"strings"
 	"time"
-	"vibe-check/internal/git"
+	"vibe-check/internal/config"
 	"vibe-check/internal/models"
+	"vibe-check/internal/scan"
 
 	"github.com/charmbracelet/lipgloss"
 )
 
+// limitStyle styles a character counter, turning red past 80% of the limit
+func limitStyle(length, limit int) lipgloss.Style {
+	if length*5 > limit*4 {
+		return ErrorStyle
+	}
+	return AppCaption
+}
+
 // RenderNoteInput renders the note input view
 func RenderNoteInput(m models.AppModel) string {
 	var s strings.Builder
@@ -17,7 +26,7 @@ func RenderNoteInput(m models.AppModel) string {
 	title := lipgloss.JoinHorizontal(lipgloss.Left,
 		InfoStyle.Render("Custom Note"),
 		"  ",
-		AppCaption.Render("Enter a note for this checkpoint"),
+		AppCaption.Render(noteCaption(m)),
 	)
 
 	// Input field
@@ -30,11 +39,9 @@ func RenderNoteInput(m models.AppModel) string {
 	noteDisplay += MenuPointer.Render("│")
 	
 	// Character counter
-	counter := fmt.Sprintf("(%d/50)", len(m.CustomNote))
-	counterStyle := AppCaption
-	if len(m.CustomNote) > 40 {
-		counterStyle = ErrorStyle
-	}
+	limit := config.Int("ui.note_limit")
+	counter := fmt.Sprintf("(%d/%d)", len(m.CustomNote), limit)
+	counterStyle := limitStyle(len(m.CustomNote), limit)
 	
 	inputSection := fmt.Sprintf("%s\n%s", 
 		MenuItem.Render(noteDisplay),
@@ -52,6 +59,71 @@ func RenderNoteInput(m models.AppModel) string {
 	return s.String()
 }
 
+// noteCaption says which changes the note being typed is for
+func noteCaption(m models.AppModel) string {
+	if m.PendingPaths != nil {
+		return "Enter a note for the selected files"
+	}
+	return "Enter a note for this checkpoint"
+}
+
+// RenderFileSelection renders the file picker of a partial checkpoint
+func RenderFileSelection(m models.AppModel) string {
+	var s strings.Builder
+
+	selected := 0
+	for _, sel := range m.FileSelected {
+		if sel {
+			selected++
+		}
+	}
+	title := lipgloss.JoinHorizontal(lipgloss.Left,
+		InfoStyle.Render("Select Files"),
+		"  ",
+		AppCaption.Render(fmt.Sprintf("%d of %d selected • the rest stays in your working tree", selected, len(m.Files))),
+	)
+
+	if len(m.Files) == 0 {
+		body := AppCaption.Render("No uncommitted changes")
+		footer := HelpStyle.Render("Esc back")
+		content := body + "\n" + Hairline.Render(strings.Repeat("─", 30)) + "\n" + footer
+		
+		s.WriteString(CardAlt.Render(title) + "\n")
+		s.WriteString(Card.Render(content))
+		return s.String()
+	}
+
+	var list strings.Builder
+	
+	for i, file := range m.Files {
+		prefix := "  "
+		lineStyle := MenuItem
+		
+		if i == m.FileCursor {
+			prefix = MenuPointer.Render("› ")
+			lineStyle = MenuItemActive
+		}
+		
+		box := "[ ]"
+		if m.FileSelected[i] {
+			box = SuccessStyle.Render("[x]")
+		}
+		
+		list.WriteString(prefix + box + " " + lineStyle.Render(file))
+		list.WriteString("\n")
+	}
+	
+	footer := HelpStyle.Render("↑/↓ navigate • Space select • a all • Enter create • n add note • Esc back")
+	dividerLine := Hairline.Render(strings.Repeat("─", 40))
+	
+	body := strings.TrimRight(list.String(), "\n") + "\n" + dividerLine + "\n" + footer
+	
+	s.WriteString(CardAlt.Render(title) + "\n")
+	s.WriteString(Card.Render(body))
+	
+	return s.String()
+}
+
 func RenderFinalizeMessageInput(m models.AppModel) string {
 	var s strings.Builder
 
@@ -71,11 +143,9 @@ func RenderFinalizeMessageInput(m models.AppModel) string {
 	messageDisplay += MenuPointer.Render("│")
 	
 	// Character counter
-	counter := fmt.Sprintf("(%d/100)", len(m.CustomCommitMessage))
-	counterStyle := AppCaption
-	if len(m.CustomCommitMessage) > 80 {
-		counterStyle = ErrorStyle
-	}
+	limit := config.Int("ui.message_limit")
+	counter := fmt.Sprintf("(%d/%d)", len(m.CustomCommitMessage), limit)
+	counterStyle := limitStyle(len(m.CustomCommitMessage), limit)
 	
 	inputSection := fmt.Sprintf("%s\n%s", 
 		MenuItem.Render(messageDisplay),
@@ -93,6 +163,75 @@ func RenderFinalizeMessageInput(m models.AppModel) string {
 	return s.String()
 }
 
+// RenderFinalizeConfirm renders the finalize plan with confirm/cancel options
+func RenderFinalizeConfirm(m models.AppModel) string {
+	var s strings.Builder
+
+	title := lipgloss.JoinHorizontal(lipgloss.Left,
+		InfoStyle.Render("Confirm Finalize"),
+		"  ",
+		AppCaption.Render("Review before history is rewritten"),
+	)
+
+	var body strings.Builder
+	if plan := m.FinalizePlan; plan != nil {
+		body.WriteString(AppCaption.Render(fmt.Sprintf("Squash %d checkpoint(s):", len(plan.Squash))) + "\n")
+		for _, cp := range plan.Squash {
+			body.WriteString(MenuItem.Render(fmt.Sprintf("  [%s] %s", cp.Hash, cp.Message)) + "\n")
+		}
+		
+		if len(plan.Drop) > 0 {
+			body.WriteString("\n" + AppCaption.Render(fmt.Sprintf("Drop %d newer checkpoint(s):", len(plan.Drop))) + "\n")
+			for _, cp := range plan.Drop {
+				body.WriteString(DisabledReasonStyle.Render(fmt.Sprintf("  [%s] %s", cp.Hash, cp.Message)) + "\n")
+			}
+		}
+		
+		body.WriteString("\n" + AppCaption.Render("Base commit: ") + MenuItem.Render(fmt.Sprintf("[%s] %s", plan.BaseCommit, plan.BaseMessage)) + "\n")
+		target := plan.Target.Remote + "/" + plan.Target.RemoteBranch
+		if plan.Target.Refspec != plan.Target.Branch {
+			target += " (refspec " + plan.Target.Refspec + ")"
+		}
+		if m.PendingNoPush {
+			target = "none - local only (" + plan.Target.Branch + ")"
+		}
+		body.WriteString(AppCaption.Render("Push target: ") + MenuItem.Render(target) + "\n")
+		
+		message := m.PendingCommitMessage
+		if message == "" {
+			message = "(auto-generated)"
+		}
+		body.WriteString(AppCaption.Render("Commit message: ") + MenuItem.Render(message) + "\n")
+		
+		if plan.DiffStat != "" {
+			body.WriteString("\n" + MenuItem.Render(plan.DiffStat) + "\n")
+		}
+	}
+	
+	body.WriteString("\n")
+	for i, choice := range m.ConfirmOptions {
+		prefix := "  "
+		itemStyle := MenuItem
+		
+		if i == m.ConfirmCursor {
+			prefix = MenuPointer.Render("› ")
+			itemStyle = MenuItemActive
+		}
+		
+		body.WriteString(itemStyle.Render(prefix+choice) + "\n")
+	}
+	
+	footer := HelpStyle.Render("↑/↓ navigate • Enter select • y confirm • n/Esc cancel")
+	dividerLine := Hairline.Render(strings.Repeat("─", 50))
+	
+	content := strings.TrimRight(body.String(), "\n") + "\n" + dividerLine + "\n" + footer
+	
+	s.WriteString(CardAlt.Render(title) + "\n")
+	s.WriteString(Card.Render(content))
+	
+	return s.String()
+}
+
 // RenderCheckpointSelection renders the checkpoint selection view
 func RenderCheckpointSelection(m models.AppModel) string {
 	var s strings.Builder
@@ -114,16 +253,25 @@ func RenderCheckpointSelection(m models.AppModel) string {
 	}
 
 	var list strings.Builder
-	
-	// Get current commit hash for comparison
-	currentCommit, _ := git.GetCurrentCommit()
+	now := time.Now()
+	day := ""
 	
 	for i, cp := range m.Checkpoints {
+		if label := models.DayLabel(cp.Time, now); m.GroupByDay && label != day {
+			day = label
+			list.WriteString(InfoStyle.Render(day) + "\n")
+		}
+		
 		prefix := "  "
 		lineStyle := MenuItem
 		
-		// Check if this is the current commit
-		isCurrentCheckpoint := cp.Hash == currentCommit
+		// Check if this is the current checkpoint
+		isCurrentCheckpoint := cp.Hash == m.CurrentCheckpoint
+		
+		message := cp.Message
+		if cp.Shadow {
+			message += " (shadow)"
+		}
 		
 		// Handle cursor selection and styling
 		if i == m.CheckpointCursor {
@@ -133,16 +281,201 @@ func RenderCheckpointSelection(m models.AppModel) string {
 		
 		// If this is the current checkpoint, render with green style regardless of cursor
 		if isCurrentCheckpoint {
-			line := fmt.Sprintf("%s%s", prefix, CurrentCheckpointStyle.Render(fmt.Sprintf("[%s] — %s", cp.Hash, cp.Message)))
+			line := fmt.Sprintf("%s%s", prefix, CurrentCheckpointStyle.Render(fmt.Sprintf("[%s] — %s", cp.Hash, message)))
 			list.WriteString(line)
 		} else {
-			line := fmt.Sprintf("%s[%s] — %s", prefix, cp.Hash, cp.Message)
+			line := fmt.Sprintf("%s[%s] — %s", prefix, cp.Hash, message)
 			list.WriteString(lineStyle.Render(line))
 		}
+		list.WriteString("  " + AppCaption.Render(fmt.Sprintf("%s · %s", models.RelativeTime(cp.Time, now), cp.ChangeSize())))
 		list.WriteString("\n")
 	}
 	
-	footer := HelpStyle.Render("↑/↓ navigate • Enter switch • Esc back")
+	footer := HelpStyle.Render("↑/↓ navigate • Enter switch • d diff • w diff vs working tree • g group by day • Esc back")
+	dividerLine := Hairline.Render(strings.Repeat("─", 40))
+	
+	body := strings.TrimRight(list.String(), "\n") + "\n" + dividerLine + "\n" + footer
+	
+	s.WriteString(CardAlt.Render(title) + "\n")
+	s.WriteString(Card.Render(body))
+	
+	return s.String()
+}
+
+// DiffPageSize returns how many diff lines fit on screen
+func DiffPageSize(m models.AppModel) int {
+	// Leave room for the title card, borders, padding and footer
+	if m.Height > 20 {
+		return m.Height - 10
+	}
+	return 20
+}
+
+// RenderDiff renders the scrollable diff viewer
+func RenderDiff(m models.AppModel) string {
+	var s strings.Builder
+
+	title := lipgloss.JoinHorizontal(lipgloss.Left,
+		InfoStyle.Render("Diff"),
+		"  ",
+		AppCaption.Render(m.DiffTitle),
+	)
+
+	if len(m.DiffLines) == 0 {
+		body := AppCaption.Render("No differences")
+		footer := HelpStyle.Render("Esc back to checkpoints")
+		content := body + "\n" + Hairline.Render(strings.Repeat("─", 30)) + "\n" + footer
+		
+		s.WriteString(CardAlt.Render(title) + "\n")
+		s.WriteString(Card.Render(content))
+		return s.String()
+	}
+
+	// Keep long lines from stretching the card past the terminal
+	width := 100
+	if m.Width > 20 {
+		width = m.Width - 8
+	}
+	
+	page := DiffPageSize(m)
+	end := m.DiffOffset + page
+	if end > len(m.DiffLines) {
+		end = len(m.DiffLines)
+	}
+	
+	var view strings.Builder
+	for _, line := range m.DiffLines[m.DiffOffset:end] {
+		line = strings.ReplaceAll(line, "\t", "    ")
+		if runes := []rune(line); len(runes) > width {
+			line = string(runes[:width])
+		}
+		view.WriteString(renderDiffLine(line) + "\n")
+	}
+	
+	position := fmt.Sprintf("lines %d-%d of %d", m.DiffOffset+1, end, len(m.DiffLines))
+	footer := HelpStyle.Render("↑/↓ scroll • PgUp/PgDn page • g/G top/bottom • Esc back  ") + AppCaption.Render(position)
+	dividerLine := Hairline.Render(strings.Repeat("─", 40))
+	
+	body := strings.TrimRight(view.String(), "\n") + "\n" + dividerLine + "\n" + footer
+	
+	s.WriteString(CardAlt.Render(title) + "\n")
+	s.WriteString(Card.Render(body))
+	
+	return s.String()
+}
+
+// renderDiffLine colors one line of a unified diff
+func renderDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "diff --git"):
+		return DiffFileStyle.Render(line)
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"),
+		strings.HasPrefix(line, "index "), strings.HasPrefix(line, "new file"), strings.HasPrefix(line, "deleted file"):
+		return DiffMetaStyle.Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return DiffHunkStyle.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return DiffAddStyle.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return DiffDelStyle.Render(line)
+	}
+	return MenuItem.Render(line)
+}
+
+// RenderHistory renders the operation journal with undo/redo keys
+func RenderHistory(m models.AppModel) string {
+	var s strings.Builder
+
+	title := lipgloss.JoinHorizontal(lipgloss.Left,
+		InfoStyle.Render("History"),
+		"  ",
+		AppCaption.Render("Recorded operations, newest first"),
+	)
+
+	if len(m.History) == 0 {
+		body := AppCaption.Render("No operations recorded yet")
+		footer := HelpStyle.Render("Esc back to main menu")
+		content := body + "\n" + Hairline.Render(strings.Repeat("─", 30)) + "\n" + footer
+		
+		s.WriteString(CardAlt.Render(title) + "\n")
+		s.WriteString(Card.Render(content))
+		return s.String()
+	}
+
+	var list strings.Builder
+	
+	for i, op := range m.History {
+		prefix := "  "
+		lineStyle := MenuItem
+		
+		if i == m.HistoryCursor {
+			prefix = MenuPointer.Render("› ")
+			lineStyle = MenuItemActive
+		}
+		if op.Undone {
+			lineStyle = DisabledStyle
+		}
+		
+		line := fmt.Sprintf("%s%s  %s", prefix, op.Time.Format("02/01 15:04:05"), op.Summary)
+		list.WriteString(lineStyle.Render(line))
+		if op.Undone {
+			list.WriteString(DisabledReasonStyle.Render(" (undone)"))
+		}
+		list.WriteString("\n")
+	}
+	
+	footer := HelpStyle.Render("↑/↓ navigate • u undo last • r redo • Esc back")
+	dividerLine := Hairline.Render(strings.Repeat("─", 40))
+	
+	body := strings.TrimRight(list.String(), "\n") + "\n" + dividerLine + "\n" + footer
+	
+	s.WriteString(CardAlt.Render(title) + "\n")
+	s.WriteString(Card.Render(body))
+	
+	return s.String()
+}
+
+// RenderBackups renders the backup branches with restore/diff/prune keys
+func RenderBackups(m models.AppModel) string {
+	var s strings.Builder
+
+	title := lipgloss.JoinHorizontal(lipgloss.Left,
+		InfoStyle.Render("Backups"),
+		"  ",
+		AppCaption.Render("Branches kept by finalize, newest first"),
+	)
+
+	if len(m.Backups) == 0 {
+		body := AppCaption.Render("No backups found")
+		footer := HelpStyle.Render("Esc back to main menu")
+		content := body + "\n" + Hairline.Render(strings.Repeat("─", 30)) + "\n" + footer
+		
+		s.WriteString(CardAlt.Render(title) + "\n")
+		s.WriteString(Card.Render(content))
+		return s.String()
+	}
+
+	var list strings.Builder
+	
+	for i, b := range m.Backups {
+		prefix := "  "
+		lineStyle := MenuItem
+		
+		if i == m.BackupCursor {
+			prefix = MenuPointer.Render("› ")
+			lineStyle = MenuItemActive
+		}
+		
+		branch := b.Branch
+		if branch == "" {
+			branch = "unknown branch"
+		}
+		line := fmt.Sprintf("%s%s  [%s] from %s", prefix, b.Time.Format("02/01 15:04"), b.Hash, branch)
+		list.WriteString(lineStyle.Render(line) + "\n")
+		list.WriteString(DisabledReasonStyle.Render("    vs HEAD: "+b.DiffStat) + "\n")
+	}
+	
+	footer := HelpStyle.Render("↑/↓ navigate • Enter restore • R force restore • d diff • p prune >" + config.String("backups.prune_age") + " • Esc back")
 	dividerLine := Hairline.Render(strings.Repeat("─", 40))
 	
 	body := strings.TrimRight(list.String(), "\n") + "\n" + dividerLine + "\n" + footer
@@ -177,6 +510,12 @@ func RenderResult(m models.AppModel) string {
 		msg = InfoStyle.Render(m.Result)
 	}
 	
+	// Scan warnings: the operation went ahead, but the user should look at these
+	if len(m.Findings) > 0 {
+		header := fmt.Sprintf("⚠ The scan found %d possible problem(s):", len(m.Findings))
+		msg += "\n\n" + ErrorStyle.Render(header) + "\n" + InfoStyle.Render(scan.Format(m.Findings))
+	}
+	
 	footer := HelpStyle.Render("Press any key to continue…")
 	dividerLine := Hairline.Render(strings.Repeat("─", 30))
 	
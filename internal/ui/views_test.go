@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"vibe-check/internal/config"
+	"vibe-check/internal/models"
+)
+
+func TestLimitStyleFollowsLimit(t *testing.T) {
+	cases := []struct {
+		length, limit int
+		warn          bool
+	}{
+		{40, 50, false},
+		{41, 50, true},
+		{80, 100, false},
+		{81, 100, true},
+		{100, 200, false},
+		{161, 200, true},
+	}
+	for _, c := range cases {
+		if got := limitStyle(c.length, c.limit).GetForeground() == ErrorStyle.GetForeground(); got != c.warn {
+			t.Errorf("limitStyle(%d, %d) warns = %v, want %v", c.length, c.limit, got, c.warn)
+		}
+	}
+}
+
+func TestBackupsFooterShowsPruneAge(t *testing.T) {
+	t.Cleanup(func() { config.Load("", nil) })
+	t.Setenv(config.EnvName("backups.prune_age"), "2w")
+	if err := config.Load("", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	view := RenderBackups(models.AppModel{Backups: []models.Backup{{Name: "vibe-check-backup-1700000000"}}})
+	if !strings.Contains(view, "p prune >2w") {
+		t.Errorf("footer does not show the configured age:\n%s", view)
+	}
+}
@@ -0,0 +1,314 @@
+// Package workspace runs checkpoint operations across several related
+// repositories together. The repositories are listed in the workspace.repos
+// setting; each one is entered in turn through its own git.Repo, with its
+// own settings loaded.
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"vibe-check/internal/config"
+	"vibe-check/internal/git"
+	"vibe-check/internal/models"
+)
+
+// ErrNoWorkspace is returned when workspace.repos is not set
+var ErrNoWorkspace = errors.New("no workspace configured. List its repositories with e.g. `vibe-check config set workspace.repos .,../web`")
+
+// Error reports a workspace operation that failed in at least one repository.
+// Results holds the outcome in every repository, including the rollbacks.
+type Error struct {
+	Op      string // create, finalize or push
+	Results []models.RepoResult
+}
+
+func (e *Error) Error() string {
+	var failed, pushed, unpushed []string
+	for _, r := range e.Results {
+		switch {
+		case r.Err != nil:
+			failed = append(failed, r.Repo)
+		case r.Pushed:
+			pushed = append(pushed, fmt.Sprintf("%s (%s/%s)", r.Repo, r.Plan.Target.Remote, r.Plan.Target.RemoteBranch))
+		case e.Op == "push" && r.Plan != nil && !r.RolledBack:
+			unpushed = append(unpushed, r.Repo)
+		}
+	}
+	msg := fmt.Sprintf("workspace %s failed in %s", e.Op, strings.Join(failed, ", "))
+	if len(pushed) > 0 {
+		msg += "\nAlready pushed, which cannot be undone: " + strings.Join(pushed, ", ")
+	}
+	if len(unpushed) > 0 {
+		msg += "\nFinalized locally but not pushed: " + strings.Join(unpushed, ", ")
+	}
+	return msg
+}
+
+// Repos returns the repositories of the workspace. Relative paths are
+// resolved against the directory of the settings file that lists them, or
+// the directory base runs as if started in (see -C) when they come from the
+// environment or a flag.
+func Repos(base *git.Repo) ([]string, error) {
+	value, err := config.Get("workspace.repos")
+	if err != nil {
+		return nil, err
+	}
+	repos := config.Strings("workspace.repos")
+	if len(repos) == 0 {
+		return nil, ErrNoWorkspace
+	}
+
+	dir := filepath.Dir(value.Path)
+	if value.Path == "" {
+		if dir, err = base.StartDir(); err != nil {
+			return nil, err
+		}
+	}
+	for i, repo := range repos {
+		if !filepath.IsAbs(repo) {
+			repos[i] = filepath.Join(dir, repo)
+		}
+	}
+	return repos, nil
+}
+
+// session visits the repositories of a workspace, each through its own Repo
+type session struct {
+	base *git.Repo // the repository vibe-check was started in
+}
+
+func begin(base *git.Repo) session {
+	return session{base: base}
+}
+
+// enter returns a Repo running git in dir and loads its settings
+func (s session) enter(dir string) (*git.Repo, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+	repo := s.base.At(dir)
+	if !repo.IsRepo() {
+		return nil, git.ErrNotRepo
+	}
+	// Checkpoints in a workspace always cover each whole repository
+	if err := repo.SetScope(git.ScopeRepo); err != nil {
+		return nil, err
+	}
+	root, _ := repo.RepoRoot()
+	return repo, config.Load(root, repo.LegacySettings())
+}
+
+// end loads the settings of the repository the session started in again
+func (s session) end() {
+	if root, err := s.base.RepoRoot(); err == nil {
+		_ = config.Load(root, s.base.LegacySettings())
+	}
+}
+
+// rollback undoes the latest operation in every repository that succeeded
+func (s session) rollback(results []models.RepoResult) {
+	if s.base.IsDryRun() {
+		return
+	}
+	for i := range results {
+		r := &results[i]
+		if r.Err != nil || r.Skipped != "" {
+			continue
+		}
+		repo, err := s.enter(r.Repo)
+		if err != nil {
+			r.Err = fmt.Errorf("rollback failed: %v", err)
+			continue
+		}
+		if _, err := repo.Undo(); err != nil {
+			r.Err = fmt.Errorf("rollback failed: %v", err)
+			continue
+		}
+		r.RolledBack = true
+	}
+}
+
+// Create checkpoints every repository with changes, using the same note.
+// If one fails, the checkpoints already made are undone.
+func Create(base *git.Repo, note string) ([]models.RepoResult, error) {
+	repos, err := Repos(base)
+	if err != nil {
+		return nil, err
+	}
+	s := begin(base)
+	defer s.end()
+
+	results := make([]models.RepoResult, 0, len(repos))
+	for _, dir := range repos {
+		result := models.RepoResult{Repo: dir}
+		repo, err := s.enter(dir)
+		if result.Err = err; err == nil {
+			result.Checkpoint, result.Err = repo.CreateCheckpointWithOptions(git.CheckpointOptions{Note: note})
+		}
+		if errors.Is(result.Err, git.ErrNoChanges) {
+			result.Skipped, result.Err = "no changes", nil
+		}
+		results = append(results, result)
+
+		if result.Err != nil {
+			s.rollback(results)
+			return results, &Error{Op: "create", Results: results}
+		}
+	}
+
+	if !created(results) {
+		return results, git.ErrNoChanges
+	}
+	return results, nil
+}
+
+// List returns the checkpoints of every repository. A repository that
+// cannot be read is reported in its result without failing the others.
+func List(base *git.Repo) ([]models.RepoResult, error) {
+	repos, err := Repos(base)
+	if err != nil {
+		return nil, err
+	}
+	s := begin(base)
+	defer s.end()
+
+	results := make([]models.RepoResult, 0, len(repos))
+	for _, dir := range repos {
+		result := models.RepoResult{Repo: dir}
+		repo, err := s.enter(dir)
+		if result.Err = err; err == nil {
+			result.Checkpoints, result.Err = repo.GetCheckpoints()
+		}
+		if result.Err == nil {
+			result.Err = repo.LoadCheckpointDetails(result.Checkpoints)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Finalize squashes the checkpoints of every repository that has some, then
+// pushes them unless push is false. Every repository is checked before any
+// is changed, and every remote is asked before any is pushed to. If squashing
+// or a push check fails in one, the others are rolled back. Pushes cannot be
+// undone, so a push that still fails stops the rest, which stay finalized locally.
+func Finalize(base *git.Repo, message string, push bool) ([]models.RepoResult, error) {
+	repos, err := Repos(base)
+	if err != nil {
+		return nil, err
+	}
+	s := begin(base)
+	defer s.end()
+
+	// Check every repository first, so most failures change nothing
+	results := make([]models.RepoResult, 0, len(repos))
+	findings := make([][]models.Finding, len(repos))
+	failed := false
+	for i, dir := range repos {
+		result := models.RepoResult{Repo: dir}
+		repo, err := s.enter(dir)
+		if result.Err = err; err == nil {
+			var plan *models.FinalizePlan
+			if plan, result.Err = repo.GetFinalizePlan(); result.Err == nil && push {
+				findings[i], result.Err = repo.ScanPush(plan)
+			}
+		}
+		switch {
+		case errors.Is(result.Err, git.ErrNoCheckpoints):
+			result.Skipped, result.Err = "no checkpoints", nil
+		case errors.Is(result.Err, git.ErrNotOnCheckpoint):
+			result.Skipped, result.Err = "not on a checkpoint", nil
+		}
+		failed = failed || result.Err != nil
+		results = append(results, result)
+	}
+	if failed {
+		return results, &Error{Op: "finalize", Results: results}
+	}
+
+	for i := range results {
+		r := &results[i]
+		if r.Skipped != "" {
+			continue
+		}
+		repo, err := s.enter(r.Repo)
+		if r.Err = err; err == nil {
+			r.Plan, r.Err = repo.SquashCheckpoints(message)
+		}
+		if r.Plan != nil {
+			r.Plan.Findings = findings[i]
+		}
+		if r.Err != nil {
+			s.rollback(results[:i])
+			return results, &Error{Op: "finalize", Results: results}
+		}
+	}
+	if !finalized(results) {
+		return results, git.ErrNoCheckpoints
+	}
+	if !push {
+		return results, nil
+	}
+
+	// Ask every remote before pushing to any, while everything can still be rolled back
+	checks := make([]error, len(results))
+	for i := range results {
+		r := &results[i]
+		if r.Skipped != "" {
+			continue
+		}
+		repo, err := s.enter(r.Repo)
+		if checks[i] = err; err == nil {
+			checks[i] = repo.CheckPush(r.Plan.Target)
+		}
+		failed = failed || checks[i] != nil
+	}
+	if failed {
+		s.rollback(results)
+		for i, err := range checks {
+			if err != nil && results[i].Err == nil {
+				results[i].Err = err
+			}
+		}
+		return results, &Error{Op: "push", Results: results}
+	}
+
+	for i := range results {
+		r := &results[i]
+		if r.Skipped != "" {
+			continue
+		}
+		repo, err := s.enter(r.Repo)
+		if r.Err = err; err == nil {
+			_, r.Err = repo.PublishTarget(r.Plan.Target) // findings were kept from the check above
+		}
+		if r.Err != nil {
+			return results, &Error{Op: "push", Results: results}
+		}
+		r.Pushed = true
+	}
+	return results, nil
+}
+
+// created reports whether any repository got a checkpoint
+func created(results []models.RepoResult) bool {
+	for _, r := range results {
+		if r.Checkpoint != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// finalized reports whether any repository was finalized
+func finalized(results []models.RepoResult) bool {
+	for _, r := range results {
+		if r.Plan != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package workspace
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"vibe-check/internal/config"
+	"vibe-check/internal/git"
+)
+
+// run runs git in dir and returns its trimmed output
+func run(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// newWorkspace creates repositories named after names under one directory,
+// each with a checkpoint and a bare origin, lists them in workspace.repos and
+// returns the directory with a Repo started in it
+func newWorkspace(t *testing.T, names ...string) (string, *git.Repo) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	root := t.TempDir()
+	for _, name := range names {
+		dir, remote := filepath.Join(root, name), filepath.Join(root, name+".git")
+		run(t, root, "init", "-q", "--bare", "-b", "main", remote)
+		run(t, root, "init", "-q", "-b", "main", dir)
+		run(t, dir, "config", "user.name", "Test")
+		run(t, dir, "config", "user.email", "test@example.com")
+		writeFile(t, filepath.Join(dir, "README.md"), "hello\n")
+		run(t, dir, "add", "-A")
+		run(t, dir, "commit", "-q", "-m", "init")
+		run(t, dir, "remote", "add", "origin", remote)
+		run(t, dir, "push", "-q", "-u", "origin", "main")
+		writeFile(t, filepath.Join(dir, "app.txt"), "change\n")
+	}
+
+	base := git.Open(root)
+	t.Cleanup(func() { config.Load("", nil) })
+	t.Setenv(config.EnvName("workspace.repos"), strings.Join(names, ","))
+	if err := config.Load("", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Create(base, "work"); err != nil {
+		t.Fatalf("workspace create: %v", err)
+	}
+	return root, base
+}
+
+func writeFile(t *testing.T, file, content string) {
+	t.Helper()
+	if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReposResolvedAgainstRepoFlag(t *testing.T) {
+	root, base := newWorkspace(t, "api", "web")
+	repos, err := Repos(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(root, "api"), filepath.Join(root, "web")}
+	if strings.Join(repos, ",") != strings.Join(want, ",") {
+		t.Errorf("Repos() = %v, want %v", repos, want)
+	}
+}
+
+func TestFinalizeRollsBackWhenAPushCheckFails(t *testing.T) {
+	root, base := newWorkspace(t, "api", "web")
+	apiHead := run(t, filepath.Join(root, "api"), "rev-parse", "HEAD")
+	webHead := run(t, filepath.Join(root, "web"), "rev-parse", "HEAD")
+
+	// Someone else pushes to web, so its lease no longer holds
+	other := t.TempDir()
+	run(t, root, "clone", "-q", filepath.Join(root, "web.git"), other)
+	run(t, other, "-c", "user.name=Other", "-c", "user.email=other@example.com", "commit", "-q", "--allow-empty", "-m", "theirs")
+	run(t, other, "push", "-q", "origin", "main")
+	apiRemote := run(t, root, "--git-dir", filepath.Join(root, "api.git"), "rev-parse", "main")
+
+	results, err := Finalize(base, "Ship it", true)
+
+	var werr *Error
+	if !errors.As(err, &werr) || werr.Op != "push" {
+		t.Fatalf("expected a workspace push error, got %v", err)
+	}
+	if got := run(t, root, "--git-dir", filepath.Join(root, "api.git"), "rev-parse", "main"); got != apiRemote {
+		t.Error("api was pushed although web's push would fail")
+	}
+	if got := run(t, filepath.Join(root, "api"), "rev-parse", "HEAD"); got != apiHead {
+		t.Errorf("api not rolled back: HEAD %s, want %s", got, apiHead)
+	}
+	if got := run(t, filepath.Join(root, "web"), "rev-parse", "HEAD"); got != webHead {
+		t.Errorf("web not rolled back: HEAD %s, want %s", got, webHead)
+	}
+	for _, r := range results {
+		if !r.RolledBack || r.Pushed {
+			t.Errorf("%s: rolled back %v, pushed %v", r.Repo, r.RolledBack, r.Pushed)
+		}
+	}
+}
+
+func TestFinalizeReportsRemotesAlreadyPushed(t *testing.T) {
+	root, base := newWorkspace(t, "api", "web")
+	// A hook only runs on a real push, so the check cannot catch it
+	hook := filepath.Join(root, "web.git", "hooks", "pre-receive")
+	if err := os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := Finalize(base, "Ship it", true)
+
+	var werr *Error
+	if !errors.As(err, &werr) {
+		t.Fatalf("expected a workspace error, got %v", err)
+	}
+	if !results[0].Pushed || results[1].Pushed || results[1].Err == nil {
+		t.Errorf("api pushed %v, web pushed %v with error %v", results[0].Pushed, results[1].Pushed, results[1].Err)
+	}
+	if msg := err.Error(); !strings.Contains(msg, "Already pushed") || !strings.Contains(msg, filepath.Join(root, "api")+" (origin/main)") {
+		t.Errorf("error does not name the remote already updated:\n%s", msg)
+	}
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
 	"vibe-check/internal/app"
+	"vibe-check/internal/config"
 	"vibe-check/internal/git"
+	"vibe-check/internal/models"
+	"vibe-check/internal/scan"
+	"vibe-check/internal/workspace"
 
 	"github.com/spf13/cobra"
 )
@@ -18,7 +28,10 @@ Tired of complicated Git commands? Vibe Check gives you a clean, intuitive inter
 for creating checkpoints, switching between versions, and cleaning up commit history.
 Perfect for AI-assisted development and experimental coding.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := app.RunApp(); err != nil {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		trace, _ := cmd.Flags().GetBool("trace")
+
+		if err := app.RunAppWithOptions(app.Options{Repo: repo, DryRun: dryRun, Trace: trace}); err != nil {
 			fmt.Printf("Error: %v\n", err)
 			os.Exit(1)
 		}
@@ -35,17 +48,24 @@ var createCmd = &cobra.Command{
 		if len(args) > 0 {
 			note = args[0]
 		}
+		shadow, _ := cmd.Flags().GetBool("shadow")
+		paths, _ := cmd.Flags().GetStringSlice("paths")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		if interactive && (jsonOutput() || dryRunner != nil) {
+			exitWithError(usageError{fmt.Errorf("--interactive prompts for each hunk and cannot run with --output json or --dry-run")})
+		}
 		
-		err := git.CreateCheckpoint(note)
+		cp, err := repo.CreateCheckpointWithOptions(git.CheckpointOptions{Note: note, Shadow: shadow, Paths: paths, Interactive: interactive})
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(err)
 		}
 		
+		result := toCheckpointJSON(*cp)
+		result.IsCurrent = !cp.Shadow
 		if note != "" {
-			fmt.Printf("✅ Checkpoint created with note: %s\n", note)
+			printResult(result, "✅ Checkpoint created with note: %s\n%s", note, findingsNote(cp.Findings))
 		} else {
-			fmt.Printf("✅ Checkpoint created\n")
+			printResult(result, "✅ Checkpoint created\n%s", findingsNote(cp.Findings))
 		}
 	},
 }
@@ -55,10 +75,30 @@ var listCmd = &cobra.Command{
 	Short: "List all checkpoints",
 	Long:  "Display all available checkpoints with their hashes and messages",
 	Run: func(cmd *cobra.Command, args []string) {
-		checkpoints, err := git.GetCheckpointsFromReflog()
+		checkpoints, err := repo.GetCheckpoints()
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(err)
+		}
+		if err := repo.LoadCheckpointDetails(checkpoints); err != nil {
+			exitWithError(err)
+		}
+		
+		// Get current checkpoint for highlighting
+		currentCommit, _ := repo.GetCurrentCheckpointHash()
+		
+		if jsonOutput() {
+			branch, _ := repo.GetCheckpointBranch()
+			result := toCheckpointsJSON(checkpoints)
+			for i := range result {
+				result[i] = result[i].withDetails(checkpoints[i])
+				result[i].IsCurrent = result[i].Hash == currentCommit
+				// The last regular commit is listed without a namespace
+				if result[i].Branch == "" {
+					result[i].Branch = branch
+				}
+			}
+			printData(result)
+			return
 		}
 		
 		if len(checkpoints) == 0 {
@@ -66,17 +106,50 @@ var listCmd = &cobra.Command{
 			return
 		}
 		
-		// Get current commit for highlighting
-		currentCommit, _ := git.GetCurrentCommit()
+		if origin := repo.GetOriginBranch(); origin != "" {
+			fmt.Printf("📍 Detached from branch %s (run `vibe-check return` to go back)\n", origin)
+		}
+		
+		byDay, _ := cmd.Flags().GetBool("by-day")
+		now := time.Now()
+		day := ""
 		
 		fmt.Println("📋 Checkpoints:")
 		for _, cp := range checkpoints {
+			if label := models.DayLabel(cp.Time, now); byDay && label != day {
+				day = label
+				fmt.Printf("\n%s\n", day)
+			}
+			
 			marker := "  "
 			if cp.Hash == currentCommit {
 				marker = "* " // Current checkpoint
 			}
-			fmt.Printf("%s[%s] %s\n", marker, cp.Hash, cp.Message)
+			suffix := ""
+			if cp.Shadow {
+				suffix = " (shadow)"
+			}
+			fmt.Printf("%s[%s] %s%s  (%s, %s)\n", marker, cp.Hash, cp.Message, suffix, models.RelativeTime(cp.Time, now), cp.ChangeSize())
+		}
+	},
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show where you are",
+	Long:  "Show the current branch or detached state, the current checkpoint, checkpoints pending finalize, uncommitted files, ahead/behind counts and backup branches",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := repo.GetStatus()
+		if err != nil {
+			exitWithError(err)
 		}
+		
+		if jsonOutput() {
+			printData(toStatusJSON(status))
+			return
+		}
+		fmt.Println(git.FormatStatus(status))
 	},
 }
 
@@ -88,13 +161,78 @@ var switchCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		hash := args[0]
 		
-		err := git.SwitchToCheckpoint(hash)
+		checkpoint, err := repo.FindCheckpoint(hash)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(err)
+		}
+		
+		if err := repo.SwitchToCheckpoint(hash); err != nil {
+			exitWithError(err)
+		}
+		
+		result := toCheckpointJSON(*checkpoint)
+		result.IsCurrent = true
+		printResult(result, "✅ Switched to checkpoint %s\n", hash)
+	},
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [from] [to]",
+	Short: "Show what changed between checkpoints",
+	Long:  "Compare two checkpoints (or any commits). from defaults to the checkpoint you are on and to defaults to the working tree.",
+	Args:  cobra.MaximumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		stat, _ := cmd.Flags().GetBool("stat")
+		nameOnly, _ := cmd.Flags().GetBool("name-only")
+		if stat && nameOnly {
+			exitWithError(usageError{fmt.Errorf("--stat and --name-only cannot be combined")})
+		}
+		
+		mode, modeName := git.DiffPatch, "patch"
+		if stat {
+			mode, modeName = git.DiffStat, "stat"
+		} else if nameOnly {
+			mode, modeName = git.DiffNameOnly, "name-only"
+		}
+		
+		var from, to string
+		if len(args) > 0 {
+			from = args[0]
+		}
+		if len(args) > 1 {
+			to = args[1]
+		}
+		
+		diff, err := repo.DiffCheckpoints(from, to, mode)
+		if err != nil {
+			exitWithError(err)
 		}
 		
-		fmt.Printf("✅ Switched to checkpoint %s\n", hash)
+		if jsonOutput() {
+			printData(map[string]string{"from": diff.From, "to": diff.To, "mode": modeName, "diff": diff.Output})
+			return
+		}
+		
+		if diff.Output == "" {
+			fmt.Printf("No differences between %s and %s\n", diff.From, diff.To)
+			return
+		}
+		fmt.Println(diff.Output)
+	},
+}
+
+var returnCmd = &cobra.Command{
+	Use:   "return",
+	Short: "Return to the branch you switched from",
+	Long:  "Leave the checkpoint you switched to and checkout the branch the switch started from",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		branch, err := repo.ReturnToOriginBranch()
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		printResult(map[string]string{"branch": branch}, "✅ Returned to branch %s\n", branch)
 	},
 }
 
@@ -109,25 +247,778 @@ var finalizeCmd = &cobra.Command{
 			message = args[0]
 		}
 		
-		err := git.FinalizeAndPushWithMessage(message)
+		if retain, _ := cmd.Flags().GetString("retain"); retain != "" {
+			if err := config.SetFlag("finalize.retention", retain); err != nil {
+				exitWithError(usageError{err})
+			}
+		}
+		
+		// Only show what would happen
+		if showPlan, _ := cmd.Flags().GetBool("plan"); showPlan {
+			if jsonOutput() {
+				plan, err := repo.GetFinalizePlan()
+				if err != nil {
+					exitWithError(err)
+				}
+				printData(toFinalizePlanJSON(plan, false))
+				return
+			}
+			info, err := repo.GetFinalizeInfo()
+			if err != nil {
+				exitWithError(err)
+			}
+			fmt.Print(info)
+			return
+		}
+		
+		// Squash locally and leave publishing for `vibe-check push`
+		if noPush, _ := cmd.Flags().GetBool("no-push"); noPush {
+			plan, err := repo.SquashCheckpoints(message)
+			if err != nil {
+				exitWithError(err)
+			}
+			printResult(toFinalizePlanJSON(plan, false), "✅ Successfully finalized locally! Run `vibe-check push` to publish.\n")
+			return
+		}
+		
+		plan, err := repo.FinalizeAndPushWithMessage(message)
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			os.Exit(1)
+			exitWithError(err)
+		}
+		
+		printResult(toFinalizePlanJSON(plan, true), "✅ Successfully finalized and pushed!\n%s", findingsNote(plan.Findings))
+	},
+}
+
+var pushCmd = &cobra.Command{
+	Use:   "push",
+	Short: "Push finalized commits to remote",
+	Long:  "Push the current branch with --force-with-lease, e.g. after `finalize --no-push`",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		findings, err := repo.Publish()
+		if err != nil {
+			exitWithError(err)
 		}
 		
-		fmt.Printf("✅ Successfully finalized and pushed!\n")
+		printResult(pushJSON{Findings: toFindingsJSON(findings)}, "✅ Successfully pushed!\n%s", findingsNote(findings))
 	},
 }
 
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the last vibe-check operation",
+	Long:  "Revert the most recent create, switch, return or finalize using the operation journal",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		op, err := repo.Undo()
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		printResult(toOperationJSON(*op), "✅ Undid: %s\n%s", op.Summary, undoNote(op))
+	},
+}
+
+var redoCmd = &cobra.Command{
+	Use:   "redo",
+	Short: "Redo the last undone operation",
+	Long:  "Replay the operation most recently reverted with undo",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		op, err := repo.Redo()
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		printResult(toOperationJSON(*op), "✅ Redid: %s\n", op.Summary)
+	},
+}
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show the operation journal",
+	Long:  "List recorded vibe-check operations, newest first; undone operations are marked",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		history, err := repo.GetHistory()
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		if jsonOutput() {
+			result := []operationJSON{}
+			for _, op := range history {
+				result = append(result, toOperationJSON(op))
+			}
+			printData(result)
+			return
+		}
+		
+		if len(history) == 0 {
+			fmt.Println("No operations recorded yet")
+			return
+		}
+		
+		fmt.Println("🕘 History:")
+		for _, op := range history {
+			suffix := ""
+			if op.Undone {
+				suffix = " (undone)"
+			}
+			fmt.Printf("  %s  %s%s\n", op.Time.Format("02/01/2006 15:04:05"), op.Summary, suffix)
+		}
+	},
+}
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "List backup branches left by finalize",
+	Long:  "List vibe-check-backup-* branches with when they were taken, their original branch and how they differ from HEAD",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		backups, err := repo.ListBackups()
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		if jsonOutput() {
+			result := []backupJSON{}
+			for _, b := range backups {
+				result = append(result, toBackupJSON(b))
+			}
+			printData(result)
+			return
+		}
+		
+		if len(backups) == 0 {
+			fmt.Println("No backups found")
+			return
+		}
+		
+		fmt.Println("🗄️  Backups:")
+		for _, b := range backups {
+			branch := b.Branch
+			if branch == "" {
+				branch = "unknown branch"
+			}
+			fmt.Printf("  %s  [%s] %s from %s\n", b.Name, b.Hash, b.Time.Format("02/01/2006 15:04"), branch)
+			fmt.Printf("      vs HEAD: %s\n", b.DiffStat)
+		}
+	},
+}
+
+var backupsRestoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Restore a backup onto its original branch",
+	Long:  "Point the backup's original branch at it, check it out and bring back its checkpoints. Refuses when the branch has commits the backup does not, unless --force is given",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool("force")
+		
+		backup, err := repo.RestoreBackup(args[0], force)
+		if errors.Is(err, git.ErrBackupBehind) && !jsonOutput() {
+			err = fmt.Errorf("%w\nRerun with --force to drop them", err)
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		printResult(toBackupJSON(*backup), "✅ Restored %s onto branch %s\n", backup.Name, backup.Branch)
+	},
+}
+
+var backupsDiffCmd = &cobra.Command{
+	Use:   "diff <backup>",
+	Short: "Show how HEAD differs from a backup",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		stat, _ := cmd.Flags().GetBool("stat")
+		
+		diff, err := repo.DiffBackup(args[0], stat)
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		if jsonOutput() {
+			printData(map[string]string{"backup": args[0], "diff": diff})
+			return
+		}
+		
+		if diff == "" {
+			fmt.Println("No differences")
+			return
+		}
+		fmt.Println(diff)
+	},
+}
+
+var backupsPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old backups",
+	Long:  "Delete backups older than --older-than (e.g. 12h, 7d, 2w), or backups.prune_age when it is not given",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if value, _ := cmd.Flags().GetString("older-than"); value != "" {
+			if err := config.SetFlag("backups.prune_age", value); err != nil {
+				exitWithError(usageError{err})
+			}
+		}
+		value := config.String("backups.prune_age")
+		age := config.Duration("backups.prune_age")
+		
+		pruned, err := repo.PruneBackups(age)
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		result := []backupJSON{}
+		for _, b := range pruned {
+			result = append(result, toBackupJSON(b))
+		}
+		if len(pruned) == 0 {
+			printResult(result, "No backups older than %s\n", value)
+			return
+		}
+		printResult(result, "✅ Pruned %d backup(s) older than %s\n", len(pruned), value)
+	},
+}
+
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Expire all reflogs and prune unreachable objects",
+	Long:  "Preview what a full cleanup would remove: every reflog entry, finalized checkpoints past their retention and the objects that become unreachable. Pass --yes to run it; this cannot be undone.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		yes, _ := cmd.Flags().GetBool("yes")
+		
+		plan, err := repo.PlanGC()
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		if !yes {
+			if jsonOutput() {
+				printData(toGCPlanJSON(plan, false))
+				return
+			}
+			fmt.Println("🧹 vibe-check gc would remove:")
+			fmt.Println(git.FormatGCPlan(plan))
+			fmt.Println("\nRun `vibe-check gc --yes` to proceed.")
+			return
+		}
+		
+		if _, err := repo.RunGC(); err != nil {
+			exitWithError(err)
+		}
+		
+		printResult(toGCPlanJSON(plan, true), "✅ Expired %d reflog entries and pruned %d unreachable object(s)\n", plan.ReflogEntries, plan.UnreachableObjects)
+	},
+}
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover [hash]",
+	Short: "Find and restore lost checkpoints",
+	Long:  "List checkpoint commits that are no longer reachable from any branch or checkpoint ref, newest first. Pass a hash to record it as a checkpoint of the current branch again, or --to-branch to create a branch at it.",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		toBranch, _ := cmd.Flags().GetString("to-branch")
+		
+		if len(args) == 0 {
+			lost, err := repo.FindLostCheckpoints()
+			if err != nil {
+				exitWithError(err)
+			}
+			
+			if jsonOutput() {
+				printData(toCheckpointsJSON(lost))
+				return
+			}
+			
+			if len(lost) == 0 {
+				fmt.Println("No lost checkpoints found")
+				return
+			}
+			
+			fmt.Println("🔎 Lost checkpoints:")
+			for _, cp := range lost {
+				fmt.Printf("  [%s] %s  %s\n", cp.Hash, cp.Time.Format("02/01/2006 15:04"), cp.Message)
+			}
+			fmt.Println("\nRun `vibe-check recover <hash>` to restore one.")
+			return
+		}
+		
+		cp, err := repo.RecoverCheckpoint(args[0], toBranch)
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		if toBranch != "" {
+			printResult(toCheckpointJSON(*cp), "✅ Recovered [%s] to branch %s\n", cp.Hash, toBranch)
+			return
+		}
+		printResult(toCheckpointJSON(*cp), "✅ Recovered [%s] as a checkpoint of %s\n", cp.Hash, cp.Branch)
+	},
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Create checkpoints automatically while you work",
+	Long: `Watch the working tree and create a checkpoint once changes have stayed
+unchanged for the quiet period. Ignored files never trigger a checkpoint. Stop with Ctrl+C.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if dryRunner != nil {
+			exitWithError(usageError{fmt.Errorf("watch creates checkpoints as it goes and cannot run with --dry-run")})
+		}
+		for flag, name := range map[string]string{"quiet-period": "watch.quiet_period", "min-interval": "watch.min_interval", "max-count": "watch.max_count"} {
+			if value, _ := cmd.Flags().GetString(flag); value != "" {
+				if err := config.SetFlag(name, value); err != nil {
+					exitWithError(usageError{err})
+				}
+			}
+		}
+		
+		opts := git.WatchOptionsFromConfig()
+		if !jsonOutput() {
+			fmt.Printf("👀 Watching for changes (checkpoint after %s without changes, Ctrl+C to stop)\n", opts.Quiet)
+		}
+		
+		count := 0
+		err := repo.Watch(opts, func(cp *models.Checkpoint) {
+			count++
+			if jsonOutput() {
+				printData(toCheckpointJSON(*cp))
+				return
+			}
+			fmt.Printf("%s ✅ [%s] %s\n", time.Now().Format("15:04:05"), cp.Hash, cp.Message)
+		})
+		if errors.Is(err, git.ErrWatchLimit) {
+			if !jsonOutput() {
+				fmt.Printf("Stopped after %d checkpoint(s) (watch.max_count)\n", count)
+			}
+			return
+		}
+		if err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Show and change settings",
+	Long: `Show and change settings. Values are read from the vibe-check.* git config
+keys of earlier versions, then ~/.config/vibe-check/config.json, then
+.vibecheck.json at the repository root, then VIBE_CHECK_* environment
+variables, then -c key=value and other command-line flags; later ones win.`,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List every setting with its value and where it comes from",
+	Long: `List every setting with its value and where it comes from: default,
+git config (a vibe-check.* key of an earlier version), global, repo, env or flag.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		values := config.List()
+		
+		if jsonOutput() {
+			result := []settingJSON{}
+			for _, v := range values {
+				result = append(result, toSettingJSON(v))
+			}
+			printData(result)
+			return
+		}
+		
+		for _, v := range values {
+			value := v.Value
+			if value == "" {
+				value = "(not set)"
+			}
+			fmt.Printf("%-34s %s  (%s)\n", v.Key.Name, value, describeSource(v))
+		}
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the value of a setting",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value, err := config.Get(args[0])
+		if err != nil {
+			exitWithError(usageError{err})
+		}
+		
+		if jsonOutput() {
+			printData(toSettingJSON(value))
+			return
+		}
+		fmt.Println(value.Value)
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Store a setting in .vibecheck.json, or the global file with --global",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		global, _ := cmd.Flags().GetBool("global")
+		root, _ := repo.RepoRoot()
+		
+		// Settings files are not part of the repository, so a dry run only validates
+		if dryRunner != nil {
+			path, err := config.FilePath(global, root)
+			if err == nil {
+				err = config.Validate(args[0], args[1])
+			}
+			if err != nil {
+				exitWithError(usageError{err})
+			}
+			fmt.Printf("🔍 Dry run - would set %s = %s in %s\n", args[0], args[1], path)
+			return
+		}
+		
+		path, err := config.Set(args[0], args[1], global, root)
+		if err != nil {
+			exitWithError(usageError{err})
+		}
+		
+		if jsonOutput() {
+			printData(map[string]string{"key": args[0], "value": args[1], "path": path})
+			return
+		}
+		fmt.Printf("✅ Set %s = %s in %s\n", args[0], args[1], path)
+	},
+}
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Checkpoint several related repositories together",
+	Long: `Create, list and finalize checkpoints across the repositories listed in the
+workspace.repos setting. If one repository fails, the others are rolled back.`,
+}
+
+var workspaceCreateCmd = &cobra.Command{
+	Use:   "create [note]",
+	Short: "Create a checkpoint in every workspace repository with changes",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var note string
+		if len(args) > 0 {
+			note = args[0]
+		}
+		
+		results, err := workspace.Create(repo, note)
+		finishWorkspace(results, err, "✅ Workspace checkpoint created\n")
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the checkpoints of every workspace repository",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		results, err := workspace.List(repo)
+		if err != nil {
+			exitWithError(err)
+		}
+		
+		if jsonOutput() {
+			printData(toRepoResultsJSON(results))
+			return
+		}
+		
+		now := time.Now()
+		for _, r := range results {
+			fmt.Printf("📁 %s\n", r.Repo)
+			switch {
+			case r.Err != nil:
+				fmt.Printf("  ❌ %v\n", r.Err)
+			case len(r.Checkpoints) == 0:
+				fmt.Println("  No checkpoints found")
+			}
+			for _, cp := range r.Checkpoints {
+				fmt.Printf("  [%s] %s  (%s, %s)\n", cp.Hash, cp.Message, models.RelativeTime(cp.Time, now), cp.ChangeSize())
+			}
+		}
+	},
+}
+
+var workspaceFinalizeCmd = &cobra.Command{
+	Use:   "finalize [message]",
+	Short: "Finalize every workspace repository with checkpoints, then push them",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var message string
+		if len(args) > 0 {
+			message = args[0]
+		}
+		noPush, _ := cmd.Flags().GetBool("no-push")
+		
+		results, err := workspace.Finalize(repo, message, !noPush)
+		if noPush {
+			finishWorkspace(results, err, "✅ Workspace finalized locally! Run `vibe-check push` in each repository to publish.\n")
+			return
+		}
+		finishWorkspace(results, err, "✅ Workspace finalized and pushed!\n")
+	},
+}
+
+// finishWorkspace reports the result of a workspace command in every
+// repository, then the overall outcome
+func finishWorkspace(results []models.RepoResult, err error, success string) {
+	if !jsonOutput() && dryRunner == nil {
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				fmt.Printf("❌ %s: %v\n", r.Repo, r.Err)
+			case r.RolledBack:
+				fmt.Printf("↩️  %s: rolled back\n", r.Repo)
+			case r.Skipped != "":
+				fmt.Printf("⏭️  %s: %s\n", r.Repo, r.Skipped)
+			case r.Checkpoint != nil:
+				fmt.Printf("✅ %s: [%s] %s\n", r.Repo, r.Checkpoint.Hash, r.Checkpoint.Message)
+				fmt.Print(findingsNote(r.Checkpoint.Findings))
+			case r.Plan != nil:
+				fmt.Printf("✅ %s: squashed %d checkpoint(s) onto %s\n", r.Repo, len(r.Plan.Squash), r.Plan.Target.Branch)
+				fmt.Print(findingsNote(r.Plan.Findings))
+			}
+		}
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	printResult(toRepoResultsJSON(results), success)
+}
+
+// findingsNote lists what the scan warned about, if anything
+func findingsNote(findings []models.Finding) string {
+	if len(findings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("⚠️  The scan found %d possible problem(s):\n%s\n", len(findings), scan.Format(findings))
+}
+
+// undoNote explains what undo cannot revert
+func undoNote(op *models.Operation) string {
+	if op.Kind == git.OpFinalize {
+		return "Note: pushes are not undone - if the finalized commit was pushed, the remote still has it.\n"
+	}
+	return ""
+}
+
+// dryRunner records the planned git commands when --dry-run is set
+var dryRunner *git.DryRunRunner
+
+// repo is the repository every command works on, set up from the global flags by configure
+var repo = git.Open("")
+
+// configure applies the global flags before any command runs
+func configure(cmd *cobra.Command, args []string) {
+	configureOutput(cmd)
+	configureRunner(cmd, args)
+	configureRepo(cmd)
+	configureSettings(cmd)
+	configurePushTarget(cmd)
+	configureScope(cmd)
+}
+
+// configureSettings loads the settings files and environment, then applies
+// the global -c key=value flags on top
+func configureSettings(cmd *cobra.Command) {
+	root, _ := repo.RepoRoot()
+	// config set must still work when a settings file is invalid, to fix it
+	if err := config.Load(root, repo.LegacySettings()); err != nil && cmd != configSetCmd {
+		exitWithError(err)
+	}
+
+	overrides, _ := cmd.Flags().GetStringArray("config")
+	for _, override := range overrides {
+		name, value, found := strings.Cut(override, "=")
+		if !found {
+			exitWithError(usageError{fmt.Errorf("invalid -c %q (use key=value)", override)})
+		}
+		if err := config.SetFlag(name, value); err != nil {
+			exitWithError(usageError{err})
+		}
+	}
+}
+
+// configureOutput applies the global --output flag
+func configureOutput(cmd *cobra.Command) {
+	format, _ := cmd.Flags().GetString("output")
+	if format != "text" && format != "json" {
+		exitWithError(usageError{fmt.Errorf("invalid output format %q (use text or json)", format)})
+	}
+	outputFormat = format
+}
+
+// configurePushTarget applies the global --remote, --branch and --refspec flags
+func configurePushTarget(cmd *cobra.Command) {
+	for flag, name := range map[string]string{"remote": "push.remote", "branch": "push.branch", "refspec": "push.refspec"} {
+		if value, _ := cmd.Flags().GetString(flag); value != "" {
+			if err := config.SetFlag(name, value); err != nil {
+				exitWithError(usageError{err})
+			}
+		}
+	}
+}
+
+// configureRepo applies the global -C/--repo flag, pointing every git command at another repository
+func configureRepo(cmd *cobra.Command) {
+	dir, _ := cmd.Flags().GetString("repo")
+	if dir == "" {
+		return
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		exitWithError(usageError{fmt.Errorf("-C %s: not a directory", dir)})
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		exitWithError(usageError{err})
+	}
+	repo.Dir = abs
+}
+
+// configureScope applies the global --scope flag and moves git commands to the repository root
+func configureScope(cmd *cobra.Command) {
+	if value, _ := cmd.Flags().GetString("scope"); value != "" {
+		if err := config.SetFlag("checkpoint.scope", value); err != nil {
+			exitWithError(usageError{err})
+		}
+	}
+	scope, err := git.ParseScope(config.String("checkpoint.scope"))
+	if err != nil {
+		exitWithError(usageError{err})
+	}
+	if err := repo.SetScope(scope); err != nil {
+		exitWithError(err)
+	}
+}
+
+// configureRunner applies the global --dry-run and --trace flags
+func configureRunner(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	trace, _ := cmd.Flags().GetBool("trace")
+
+	opts := git.RunnerOptions{DryRun: dryRun}
+	if trace {
+		opts.Trace = func(line string) {
+			fmt.Fprintln(os.Stderr, line)
+		}
+	}
+	repo.Runner, dryRunner = git.NewRunner(opts)
+}
+
+// printSuccess prints a success message, or the planned git commands during a dry run
+func printSuccess(format string, args ...interface{}) {
+	if dryRunner != nil {
+		printPlan()
+		return
+	}
+	fmt.Printf(format, args...)
+}
+
+// printPlan prints the git commands recorded during a dry run
+func printPlan() {
+	fmt.Println("🔍 Dry run - nothing was changed. Planned git commands:")
+	fmt.Println(git.FormatPlan(dryRunner.Plan()))
+}
+
+// exitWithError prints err (and any dry-run plan so far) and exits.
+// In JSON mode the error is printed as a structured object.
+func exitWithError(err error) {
+	if jsonOutput() {
+		out := jsonResult{OK: false, Error: describeError(err)}
+		if dryRunner != nil {
+			out.DryRun, out.Plan = true, plannedCommands()
+		}
+		printJSON(out)
+		os.Exit(1)
+	}
+	
+	fmt.Printf("Error: %v\n", err)
+	if dryRunner != nil {
+		printPlan()
+	}
+	os.Exit(1)
+}
+
 func init() {
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Print the git commands that would change the repository without running them")
+	rootCmd.PersistentFlags().Bool("trace", false, "Log every git command with its duration and exit status to stderr")
+	rootCmd.PersistentFlags().String("output", "text", "Output format: text or json")
+	rootCmd.PersistentFlags().String("remote", "", "Remote to push finalized work to (default: the branch's upstream remote)")
+	rootCmd.PersistentFlags().String("branch", "", "Branch to finalize onto and push to (default: the current branch's upstream)")
+	rootCmd.PersistentFlags().String("refspec", "", "Refspec passed to git push (default: <branch>[:<upstream branch>])")
+	rootCmd.PersistentFlags().StringP("repo", "C", "", "Run as if vibe-check was started in this directory")
+	rootCmd.PersistentFlags().String("scope", "", "What checkpoints cover: repo (every change, the default) or cwd (changes under the current directory)")
+	rootCmd.PersistentFlags().StringArrayP("config", "c", nil, "Override a setting for this run, e.g. -c ui.note_limit=80 (repeatable)")
+	rootCmd.PersistentPreRun = configure
+
+	createCmd.Flags().Bool("shadow", false, "Snapshot into a side ref without moving the current branch")
+	createCmd.Flags().StringSlice("paths", nil, "Only checkpoint changes to these paths (comma-separated or repeated); other changes stay in the working tree")
+	createCmd.Flags().BoolP("interactive", "i", false, "Choose the hunks to checkpoint, like git add --patch")
+	listCmd.Flags().Bool("by-day", false, "Group checkpoints under the day they were made")
+	diffCmd.Flags().Bool("stat", false, "Show a diffstat instead of the full patch")
+	diffCmd.Flags().Bool("name-only", false, "Show only the names of changed files")
+	finalizeCmd.Flags().Bool("plan", false, "Show which checkpoints would be squashed and dropped, then exit")
+	finalizeCmd.Flags().Bool("no-push", false, "Squash checkpoints locally without pushing")
+	finalizeCmd.Flags().String("retain", "", "Keep finalized checkpoints for this long (e.g. 7d); defaults to finalize.retention")
+	gcCmd.Flags().Bool("yes", false, "Run the cleanup instead of previewing it")
+	recoverCmd.Flags().String("to-branch", "", "Create this branch at the recovered checkpoint instead")
+	backupsRestoreCmd.Flags().Bool("force", false, "Restore even if commits made on the branch since the backup would be dropped")
+	backupsDiffCmd.Flags().Bool("stat", false, "Show a diffstat instead of the full patch")
+	backupsPruneCmd.Flags().String("older-than", "", "Delete backups older than this age (e.g. 12h, 7d, 2w); defaults to backups.prune_age")
+	backupsCmd.AddCommand(backupsRestoreCmd)
+	backupsCmd.AddCommand(backupsDiffCmd)
+	backupsCmd.AddCommand(backupsPruneCmd)
+	watchCmd.Flags().String("quiet-period", "", "Checkpoint after the working tree stays unchanged this long (default: watch.quiet_period)")
+	watchCmd.Flags().String("min-interval", "", "Minimum time between automatic checkpoints (default: watch.min_interval)")
+	watchCmd.Flags().String("max-count", "", "Stop after this many checkpoints (default: watch.max_count)")
+	workspaceFinalizeCmd.Flags().Bool("no-push", false, "Squash checkpoints locally without pushing")
+	workspaceCmd.AddCommand(workspaceCreateCmd)
+	workspaceCmd.AddCommand(workspaceListCmd)
+	workspaceCmd.AddCommand(workspaceFinalizeCmd)
+	configSetCmd.Flags().Bool("global", false, "Write to the global settings file instead of the repository's")
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+
 	rootCmd.AddCommand(createCmd)
 	rootCmd.AddCommand(listCmd) 
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(switchCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(returnCmd)
 	rootCmd.AddCommand(finalizeCmd)
+	rootCmd.AddCommand(pushCmd)
+	rootCmd.AddCommand(undoCmd)
+	rootCmd.AddCommand(redoCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(backupsCmd)
+	rootCmd.AddCommand(gcCmd)
+	rootCmd.AddCommand(recoverCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(workspaceCmd)
 }
 
 func main() {
+	// Cancel running git commands on Ctrl+C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	repo.Ctx = ctx
+
 	if err := rootCmd.Execute(); err != nil {
+		// Flag and argument errors are reported before configure runs
+		if format, _ := rootCmd.PersistentFlags().GetString("output"); format == "json" {
+			outputFormat = format
+			exitWithError(usageError{err})
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"vibe-check/internal/config"
+	"vibe-check/internal/git"
+	"vibe-check/internal/models"
+	"vibe-check/internal/scan"
+	"vibe-check/internal/workspace"
+)
+
+// outputFormat is set by the global --output flag: "text" or "json"
+var outputFormat = "text"
+
+// jsonOutput reports whether commands print JSON instead of text
+func jsonOutput() bool {
+	return outputFormat == "json"
+}
+
+// usageError marks errors caused by invalid flags or arguments
+type usageError struct {
+	error
+}
+
+func (e usageError) Unwrap() error {
+	return e.error
+}
+
+// jsonResult is the envelope every command prints in JSON mode
+type jsonResult struct {
+	OK     bool        `json:"ok"`
+	DryRun bool        `json:"dry_run,omitempty"`
+	Plan   []string    `json:"plan,omitempty"` // git commands a dry run would have run
+	Result interface{} `json:"result,omitempty"`
+	Error  *jsonError  `json:"error,omitempty"`
+}
+
+type jsonError struct {
+	Code        string      `json:"code"`
+	Message     string      `json:"message"`
+	Remediation string      `json:"remediation,omitempty"`
+	Details     interface{} `json:"details,omitempty"`
+}
+
+type checkpointJSON struct {
+	Hash      string `json:"hash"`
+	SHA       string `json:"sha,omitempty"`
+	Message   string `json:"message"`
+	Note      string `json:"note,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Branch    string `json:"branch,omitempty"`
+	ID        string `json:"id,omitempty"`
+	Ref       string `json:"ref,omitempty"`
+	Shadow    bool   `json:"shadow"`
+	IsCurrent bool   `json:"is_current"`
+
+	// Set by create when scan.mode is warn
+	Findings []findingJSON `json:"findings,omitempty"`
+
+	// Set by list, which loads checkpoint details
+	Author     string     `json:"author,omitempty"`
+	AuthorTime string     `json:"author_time,omitempty"`
+	DiffStat   string     `json:"diffstat,omitempty"`
+	Stats      *statsJSON `json:"stats,omitempty"`
+}
+
+type findingJSON struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// pushJSON is the result of push
+type pushJSON struct {
+	Findings []findingJSON `json:"findings,omitempty"` // set when scan.mode is warn
+}
+
+type statsJSON struct {
+	FilesChanged int `json:"files_changed"`
+	Insertions   int `json:"insertions"`
+	Deletions    int `json:"deletions"`
+}
+
+type pushTargetJSON struct {
+	Remote       string `json:"remote"`
+	Branch       string `json:"branch"`
+	RemoteBranch string `json:"remote_branch"`
+	Refspec      string `json:"refspec"`
+}
+
+type finalizePlanJSON struct {
+	Squashed    []checkpointJSON `json:"squashed"`
+	Dropped     []checkpointJSON `json:"dropped"`
+	BaseCommit  string           `json:"base_commit"`
+	BaseMessage string           `json:"base_message"`
+	Target      pushTargetJSON   `json:"target"`
+	DiffStat    string           `json:"diffstat,omitempty"`
+	Pushed      bool             `json:"pushed"`
+	Findings    []findingJSON    `json:"findings,omitempty"`
+}
+
+type operationJSON struct {
+	ID      string `json:"id"`
+	Kind    string `json:"kind"`
+	Time    string `json:"time"`
+	Summary string `json:"summary"`
+	Undone  bool   `json:"undone"`
+}
+
+type backupJSON struct {
+	Name     string `json:"name"`
+	Hash     string `json:"hash"`
+	Time     string `json:"time"`
+	Branch   string `json:"branch,omitempty"`
+	DiffStat string `json:"diffstat"`
+}
+
+type gcPlanJSON struct {
+	ReflogEntries      int              `json:"reflog_entries"`
+	ExpiredCheckpoints []string         `json:"expired_checkpoints"`
+	UnreachableObjects int              `json:"unreachable_objects"`
+	UnreachableCommits []checkpointJSON `json:"unreachable_commits"`
+	Applied            bool             `json:"applied"`
+}
+
+type settingJSON struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Source      string `json:"source"`
+	Path        string `json:"path,omitempty"`
+	Default     string `json:"default"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+type repoResultJSON struct {
+	Repo        string            `json:"repo"`
+	OK          bool              `json:"ok"`
+	Skipped     string            `json:"skipped,omitempty"`
+	RolledBack  bool              `json:"rolled_back"`
+	Error       string            `json:"error,omitempty"`
+	Checkpoint  *checkpointJSON   `json:"checkpoint,omitempty"`
+	Checkpoints []checkpointJSON  `json:"checkpoints,omitempty"`
+	Finalize    *finalizePlanJSON `json:"finalize,omitempty"`
+}
+
+type statusJSON struct {
+	Branch       string              `json:"branch,omitempty"`
+	Detached     bool                `json:"detached"`
+	OriginBranch string              `json:"origin_branch,omitempty"`
+	Current      *checkpointJSON     `json:"current,omitempty"`
+	Pending      int                 `json:"pending"`
+	Changes      map[string][]string `json:"changes"`
+	Upstream     string              `json:"upstream,omitempty"`
+	Ahead        int                 `json:"ahead"`
+	Behind       int                 `json:"behind"`
+	Backups      []string            `json:"backups"`
+}
+
+// formatTime renders a time for JSON, leaving unknown times empty
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func toCheckpointJSON(cp models.Checkpoint) checkpointJSON {
+	return checkpointJSON{
+		Hash:     cp.Hash,
+		SHA:      cp.SHA,
+		Message:  cp.Message,
+		Note:     cp.Note,
+		Time:     formatTime(cp.Time),
+		Branch:   cp.Branch,
+		ID:       cp.ID,
+		Ref:      cp.Ref,
+		Shadow:   cp.Shadow,
+		Findings: toFindingsJSON(cp.Findings),
+	}
+}
+
+func toFindingsJSON(findings []models.Finding) []findingJSON {
+	var out []findingJSON
+	for _, f := range findings {
+		out = append(out, findingJSON{Path: f.Path, Line: f.Line, Rule: f.Rule, Message: f.Message})
+	}
+	return out
+}
+
+// withDetails adds what git.LoadCheckpointDetails filled in
+func (c checkpointJSON) withDetails(cp models.Checkpoint) checkpointJSON {
+	c.Author = cp.Author
+	c.AuthorTime = formatTime(cp.AuthorTime)
+	c.DiffStat = cp.ShortStat() // git's wording, as before stats was added
+	c.Stats = &statsJSON{FilesChanged: cp.FilesChanged, Insertions: cp.Insertions, Deletions: cp.Deletions}
+	return c
+}
+
+func toCheckpointsJSON(checkpoints []models.Checkpoint) []checkpointJSON {
+	out := []checkpointJSON{}
+	for _, cp := range checkpoints {
+		out = append(out, toCheckpointJSON(cp))
+	}
+	return out
+}
+
+func toFinalizePlanJSON(plan *models.FinalizePlan, pushed bool) finalizePlanJSON {
+	return finalizePlanJSON{
+		Squashed:    toCheckpointsJSON(plan.Squash),
+		Dropped:     toCheckpointsJSON(plan.Drop),
+		BaseCommit:  plan.BaseCommit,
+		BaseMessage: plan.BaseMessage,
+		Target: pushTargetJSON{
+			Remote:       plan.Target.Remote,
+			Branch:       plan.Target.Branch,
+			RemoteBranch: plan.Target.RemoteBranch,
+			Refspec:      plan.Target.Refspec,
+		},
+		DiffStat: plan.DiffStat,
+		Pushed:   pushed,
+		Findings: toFindingsJSON(plan.Findings),
+	}
+}
+
+func toOperationJSON(op models.Operation) operationJSON {
+	return operationJSON{
+		ID:      op.ID,
+		Kind:    op.Kind,
+		Time:    formatTime(op.Time),
+		Summary: op.Summary,
+		Undone:  op.Undone,
+	}
+}
+
+func toBackupJSON(b models.Backup) backupJSON {
+	return backupJSON{
+		Name:     b.Name,
+		Hash:     b.Hash,
+		Time:     formatTime(b.Time),
+		Branch:   b.Branch,
+		DiffStat: b.DiffStat,
+	}
+}
+
+func toGCPlanJSON(plan *models.GCPlan, applied bool) gcPlanJSON {
+	expired := plan.ExpiredCheckpoints
+	if expired == nil {
+		expired = []string{}
+	}
+	return gcPlanJSON{
+		ReflogEntries:      plan.ReflogEntries,
+		ExpiredCheckpoints: expired,
+		UnreachableObjects: plan.UnreachableObjects,
+		UnreachableCommits: toCheckpointsJSON(plan.UnreachableCommits),
+		Applied:            applied,
+	}
+}
+
+func toSettingJSON(v config.Value) settingJSON {
+	return settingJSON{
+		Key:         v.Key.Name,
+		Value:       v.Value,
+		Source:      string(v.Source),
+		Path:        v.Path,
+		Default:     v.Key.Default,
+		Type:        v.Key.Kind.String(),
+		Description: v.Key.Description,
+	}
+}
+
+// describeSource says where a setting's value came from, e.g. "repo: /src/app/.vibecheck.json"
+func describeSource(v config.Value) string {
+	switch {
+	case v.Path != "":
+		return string(v.Source) + ": " + v.Path
+	case v.Source == config.SourceEnv:
+		return "env: " + config.EnvName(v.Key.Name)
+	default:
+		return string(v.Source)
+	}
+}
+
+func toStatusJSON(status *models.Status) statusJSON {
+	out := statusJSON{
+		Branch:       status.Branch,
+		Detached:     status.Detached,
+		OriginBranch: status.OriginBranch,
+		Pending:      status.Pending,
+		Changes:      map[string][]string{},
+		Upstream:     status.Upstream,
+		Ahead:        status.Ahead,
+		Behind:       status.Behind,
+		Backups:      []string{},
+	}
+	if status.Current != nil {
+		current := toCheckpointJSON(*status.Current)
+		current.IsCurrent = true
+		out.Current = &current
+	}
+	for _, group := range status.Changes {
+		out.Changes[group.Status] = group.Files
+	}
+	out.Backups = append(out.Backups, status.Backups...)
+	return out
+}
+
+func toRepoResultsJSON(results []models.RepoResult) []repoResultJSON {
+	out := []repoResultJSON{}
+	for _, r := range results {
+		item := repoResultJSON{Repo: r.Repo, OK: r.Err == nil, Skipped: r.Skipped, RolledBack: r.RolledBack}
+		if r.Err != nil {
+			item.Error = r.Err.Error()
+		}
+		if r.Checkpoint != nil {
+			cp := toCheckpointJSON(*r.Checkpoint)
+			item.Checkpoint = &cp
+		}
+		for _, cp := range r.Checkpoints {
+			item.Checkpoints = append(item.Checkpoints, toCheckpointJSON(cp).withDetails(cp))
+		}
+		if r.Plan != nil {
+			plan := toFinalizePlanJSON(r.Plan, r.Pushed)
+			item.Finalize = &plan
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to encode output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// printData prints the result of a read-only command in JSON mode
+func printData(result interface{}) {
+	printJSON(jsonResult{OK: true, Result: result})
+}
+
+// printResult prints the outcome of a command that changes the repository:
+// result as JSON in JSON mode, otherwise the formatted text. During a dry run
+// the planned git commands are printed instead.
+func printResult(result interface{}, format string, args ...interface{}) {
+	if !jsonOutput() {
+		printSuccess(format, args...)
+		return
+	}
+	out := jsonResult{OK: true, Result: result}
+	if dryRunner != nil {
+		out.DryRun, out.Plan, out.Result = true, plannedCommands(), nil
+	}
+	printJSON(out)
+}
+
+// plannedCommands lists the git commands recorded during a dry run
+func plannedCommands() []string {
+	plan := []string{}
+	for _, cmd := range dryRunner.Plan() {
+		plan = append(plan, git.FormatCommand(cmd.Args))
+	}
+	return plan
+}
+
+// describeError classifies err into a stable code with remediation for JSON output
+func describeError(err error) *jsonError {
+	desc := &jsonError{Message: err.Error()}
+
+	var finalizeErr *git.FinalizeError
+	var configErr *config.Error
+	var commandErr *git.CommandError
+	var workspaceErr *workspace.Error
+	var scanErr *scan.Error
+	var usageErr usageError
+	switch {
+	case errors.As(err, &usageErr):
+		desc.Code, desc.Remediation = "invalid_usage", "Run `vibe-check <command> --help` for the accepted flags and arguments."
+	case errors.As(err, &configErr):
+		desc.Code, desc.Remediation = "invalid_config", "Fix the setting, or run `vibe-check config list` to see every setting and where it comes from."
+		desc.Details = map[string]string{"key": configErr.Key, "path": configErr.Path}
+	case errors.As(err, &workspaceErr):
+		desc.Code, desc.Remediation = "workspace_failed", "See each repository's result. Repositories marked rolled_back are as they were before the command."
+		desc.Details = toRepoResultsJSON(workspaceErr.Results)
+	case errors.Is(err, workspace.ErrNoWorkspace):
+		desc.Code, desc.Remediation = "no_workspace", "Set workspace.repos to the repositories of the workspace."
+	case errors.As(err, &scanErr):
+		desc.Code, desc.Remediation = "scan_blocked", "Remove the secrets or large files, list accepted ones in the allowlist file (scan.allowlist), or set scan.mode to warn."
+		desc.Details = toFindingsJSON(scanErr.Findings)
+	case errors.Is(err, git.ErrNotRepo):
+		desc.Code, desc.Remediation = "not_a_repository", "Run vibe-check inside a Git repository, or create one with `git init`."
+	case errors.Is(err, git.ErrNoChanges):
+		desc.Code, desc.Remediation = "no_changes", "Edit some files, then create the checkpoint."
+	case errors.Is(err, git.ErrCheckpointNotFound):
+		desc.Code, desc.Remediation = "checkpoint_not_found", "Run `vibe-check list` to see the available checkpoints."
+	case errors.Is(err, git.ErrNoCheckpoints):
+		desc.Code, desc.Remediation = "no_checkpoints", "Create a checkpoint with `vibe-check create` first."
+	case errors.Is(err, git.ErrNotOnCheckpoint):
+		desc.Code, desc.Remediation = "not_on_checkpoint", "Switch to a checkpoint with `vibe-check switch <hash>` before finalizing."
+	case errors.Is(err, git.ErrNoOriginBranch):
+		desc.Code, desc.Remediation = "no_origin_branch", "Checkout a branch with `git checkout <branch>`."
+	case errors.Is(err, git.ErrDetachedHead):
+		desc.Code, desc.Remediation = "detached_head", "Run `vibe-check return` or checkout a branch before pushing."
+	case errors.Is(err, git.ErrUncapturedChanges):
+		desc.Code, desc.Remediation = "uncommitted_changes", "Create a checkpoint with `vibe-check create` so the changes are not lost."
+	case errors.Is(err, git.ErrBackupBehind):
+		desc.Code, desc.Remediation = "backup_behind", "Run `vibe-check backups restore --force <backup>` to drop the commits listed, or cherry-pick them after restoring."
+	case errors.Is(err, git.ErrNothingToUndo):
+		desc.Code, desc.Remediation = "nothing_to_undo", "Run `vibe-check history` to see recorded operations."
+	case errors.Is(err, git.ErrNothingToRedo):
+		desc.Code, desc.Remediation = "nothing_to_redo", "Only operations reverted with `vibe-check undo` can be redone."
+	case errors.As(err, &finalizeErr):
+		desc.Code = "finalize_failed"
+		details := map[string]interface{}{"step": finalizeErr.Step, "restored": finalizeErr.Restored}
+		if finalizeErr.Backup != "" {
+			details["backup"] = finalizeErr.Backup
+		}
+		if finalizeErr.RollbackErr != nil {
+			details["rollback_error"] = finalizeErr.RollbackErr.Error()
+			desc.Remediation = fmt.Sprintf("The rollback failed. Restore your work with `git reset --hard %s`.", finalizeErr.Backup)
+		} else {
+			desc.Remediation = "The repository was rolled back. Fix the cause and run finalize again."
+		}
+		desc.Details = details
+	case errors.As(err, &commandErr):
+		desc.Code, desc.Remediation = "git_failed", "Run again with --trace to see the failing git command."
+	default:
+		desc.Code, desc.Remediation = "failed", "Run again with --trace to see the git commands involved."
+	}
+	return desc
+}